@@ -0,0 +1,455 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/calibration"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	FlagSource       = "source"
+	FlagBins         = "bins"
+	FlagStep         = "step"
+	FlagMinPrecision = "min-precision"
+)
+
+func init() {
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions and purpose_assessments tables")
+	cmd.Flags().Int(FlagBins, 10, "number of bins of the reliability diagrams")
+	cmd.Flags().Float64(FlagStep, 0.05, "distance between the thresholds precision and recall are computed at")
+	cmd.Flags().Float64(FlagMinPrecision, 0.9, "precision of the conservative recommended threshold")
+}
+
+func main() {
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "calibrate IN_DIR LABELS_FILE OUT_FILE",
+	Short: "Measure how well purpose certainty scores match hand labels",
+	Long: `Measure how well purpose certainty scores match hand labels.
+
+LABELS_FILE is a CSV file with the header software_mention_id,purpose,label
+where purpose is one of used, created, or shared and label is true or false.
+Each label is compared to the purpose's certainty_score at both scopes. If the
+mentions table has purpose columns, as when mentions are extracted with
+--purpose-layout=wide, the scores are read from them instead of from the
+purpose_assessments table.
+
+For each scope and purpose, reports a binned reliability diagram, the Brier
+score, and the precision and recall of deciding scores of at least each
+threshold from --step to 1-step. Two thresholds are recommended: the one with
+the best F1, and the lowest with a precision of at least --min-precision.
+
+The format is chosen by the extension of OUT_FILE, .json or .csv. As CSV,
+OUT_FILE has the summary and recommendations, and the reliability diagrams and
+thresholds are written next to it, to files ending in .reliability.csv and
+.thresholds.csv.`,
+	Args:    cobra.ExactArgs(3),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrCalibrate = errors.New("calibrating purpose scores")
+
+const (
+	jsonExt = ".json"
+	csvExt  = ".csv"
+)
+
+var labelsHeader = []string{tables.SoftwareMentionIdFieldName, tables.PurposeFieldName, "label"}
+
+type mentionPurpose struct {
+	mentionId string
+	purpose   string
+}
+
+type scopePurpose struct {
+	scope   string
+	purpose string
+}
+
+type report struct {
+	scope     string
+	purpose   string
+	samples   []calibration.Sample
+	positives int
+	brier     float64
+	bins      []calibration.Bin
+	points    []calibration.Point
+
+	bestF1          calibration.Point
+	hasBestF1       bool
+	minPrecision    calibration.Point
+	hasMinPrecision bool
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inDir := args[0]
+	labelsPath := args[1]
+	outPath := args[2]
+	switch ext := filepath.Ext(outPath); ext {
+	case jsonExt, csvExt:
+	default:
+		return fmt.Errorf("%w: unknown output file extension %q, want %s or %s", ErrCalibrate, ext, jsonExt, csvExt)
+	}
+
+	source, err := cmd.Flags().GetString(FlagSource)
+	if err != nil {
+		return err
+	}
+	nBins, err := cmd.Flags().GetInt(FlagBins)
+	if err != nil {
+		return err
+	}
+	step, err := cmd.Flags().GetFloat64(FlagStep)
+	if err != nil {
+		return err
+	}
+	minPrecision, err := cmd.Flags().GetFloat64(FlagMinPrecision)
+	if err != nil {
+		return err
+	}
+	if nBins < 1 {
+		return fmt.Errorf("%w: --%s must be positive", ErrCalibrate, FlagBins)
+	}
+	if step <= 0 || step >= 1 {
+		return fmt.Errorf("%w: --%s must be between 0 and 1", ErrCalibrate, FlagStep)
+	}
+
+	labels, err := readLabels(labelsPath)
+	if err != nil {
+		return fmt.Errorf("%w: reading labels %q: %w", ErrCalibrate, labelsPath, err)
+	}
+
+	purposes, err := subsets.OpenPurposes(inDir, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCalibrate, err)
+	}
+
+	samples := make(map[scopePurpose][]calibration.Sample)
+	matched := make(map[mentionPurpose]bool)
+	err = purposes.ScanScores(ctx, func(mentionId, scope, purpose string, score float64) error {
+		key := mentionPurpose{mentionId: strings.TrimSpace(mentionId), purpose: purpose}
+		label, found := labels[key]
+		if !found {
+			return nil
+		}
+
+		matched[key] = true
+		sp := scopePurpose{scope: scope, purpose: purpose}
+		samples[sp] = append(samples[sp], calibration.Sample{Score: score, Label: label})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading purpose assessments: %w", ErrCalibrate, err)
+	}
+
+	if missing := len(labels) - len(matched); missing > 0 {
+		fmt.Printf("labels without purpose assessments: %d\n", missing)
+	}
+
+	thresholds := calibration.Thresholds(step)
+	var reports []*report
+	for _, scope := range tables.Scopes {
+		for _, purpose := range tables.Purposes {
+			s := samples[scopePurpose{scope: scope, purpose: purpose}]
+			if len(s) == 0 {
+				continue
+			}
+
+			r := &report{
+				scope:   scope,
+				purpose: purpose,
+				samples: s,
+				brier:   calibration.Brier(s),
+				bins:    calibration.Reliability(s, nBins),
+				points:  calibration.Curve(s, thresholds),
+			}
+			for _, sample := range s {
+				if sample.Label {
+					r.positives++
+				}
+			}
+			r.bestF1, r.hasBestF1 = calibration.BestF1(r.points)
+			r.minPrecision, r.hasMinPrecision = calibration.MinPrecision(r.points, minPrecision)
+			reports = append(reports, r)
+
+			fmt.Printf("%s;%s;samples=%d;brier=%.4f;best_f1_threshold=%s;min_precision_threshold=%s\n",
+				scope, purpose, len(s), r.brier,
+				formatThreshold(r.bestF1, r.hasBestF1), formatThreshold(r.minPrecision, r.hasMinPrecision))
+		}
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("%w: no labels match purpose assessments in %q", ErrCalibrate, inDir)
+	}
+
+	if filepath.Ext(outPath) == jsonExt {
+		err = writeJson(outPath, reports, minPrecision)
+	} else {
+		err = writeCsv(outPath, reports)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: writing %q: %w", ErrCalibrate, outPath, err)
+	}
+
+	return nil
+}
+
+// readLabels reads the label of each mention and purpose in the CSV file at path.
+func readLabels(path string) (map[mentionPurpose]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(labelsHeader)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if !slices.Equal(header, labelsHeader) {
+		return nil, fmt.Errorf("got header %v but want %v", header, labelsHeader)
+	}
+
+	labels := make(map[mentionPurpose]bool)
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		purpose := strings.TrimSpace(record[1])
+		if !slices.Contains(tables.Purposes, purpose) {
+			return nil, fmt.Errorf("unknown purpose %q, want one of %v", record[1], tables.Purposes)
+		}
+
+		var label bool
+		switch strings.ToLower(strings.TrimSpace(record[2])) {
+		case "true", "t", "yes", "y", "1":
+			label = true
+		case "false", "f", "no", "n", "0":
+			label = false
+		default:
+			return nil, fmt.Errorf("invalid label %q, want true or false", record[2])
+		}
+
+		labels[mentionPurpose{mentionId: strings.TrimSpace(record[0]), purpose: purpose}] = label
+	}
+
+	return labels, nil
+}
+
+func formatThreshold(p calibration.Point, found bool) string {
+	if !found {
+		return ""
+	}
+	return strconv.FormatFloat(p.Threshold, 'g', 4, 64)
+}
+
+// formatFloat formats x for CSV files, leaving undefined values empty.
+func formatFloat(x float64) string {
+	if math.IsNaN(x) {
+		return ""
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// nullable returns x for JSON files, with undefined values null.
+func nullable(x float64) *float64 {
+	if math.IsNaN(x) {
+		return nil
+	}
+	return &x
+}
+
+type jsonBin struct {
+	Lower        float64  `json:"lower"`
+	Upper        float64  `json:"upper"`
+	N            int      `json:"n"`
+	MeanScore    *float64 `json:"mean_score"`
+	PositiveRate *float64 `json:"positive_rate"`
+}
+
+type jsonPoint struct {
+	Threshold      float64  `json:"threshold"`
+	TruePositives  int      `json:"true_positives"`
+	FalsePositives int      `json:"false_positives"`
+	FalseNegatives int      `json:"false_negatives"`
+	TrueNegatives  int      `json:"true_negatives"`
+	Precision      *float64 `json:"precision"`
+	Recall         *float64 `json:"recall"`
+	F1             *float64 `json:"f1"`
+}
+
+type jsonReport struct {
+	Scope     string      `json:"scope"`
+	Purpose   string      `json:"purpose"`
+	Samples   int         `json:"samples"`
+	Positives int         `json:"positives"`
+	Brier     float64     `json:"brier"`
+	Bins      []jsonBin   `json:"reliability"`
+	Points    []jsonPoint `json:"thresholds"`
+	// BestF1 and MinPrecision are the recommended thresholds, if any.
+	BestF1       *jsonPoint `json:"best_f1"`
+	MinPrecision *jsonPoint `json:"min_precision"`
+}
+
+func toJsonPoint(p calibration.Point) jsonPoint {
+	return jsonPoint{
+		Threshold:      p.Threshold,
+		TruePositives:  p.TruePositives,
+		FalsePositives: p.FalsePositives,
+		FalseNegatives: p.FalseNegatives,
+		TrueNegatives:  p.TrueNegatives,
+		Precision:      nullable(p.Precision),
+		Recall:         nullable(p.Recall),
+		F1:             nullable(p.F1),
+	}
+}
+
+func writeJson(outPath string, reports []*report, minPrecision float64) error {
+	result := struct {
+		MinPrecision float64      `json:"min_precision"`
+		Reports      []jsonReport `json:"reports"`
+	}{MinPrecision: minPrecision}
+
+	for _, r := range reports {
+		jr := jsonReport{
+			Scope:     r.scope,
+			Purpose:   r.purpose,
+			Samples:   len(r.samples),
+			Positives: r.positives,
+			Brier:     r.brier,
+		}
+		for _, bin := range r.bins {
+			jr.Bins = append(jr.Bins, jsonBin{
+				Lower:        bin.Lower,
+				Upper:        bin.Upper,
+				N:            bin.N,
+				MeanScore:    nullable(bin.MeanScore),
+				PositiveRate: nullable(bin.PositiveRate),
+			})
+		}
+		for _, p := range r.points {
+			jr.Points = append(jr.Points, toJsonPoint(p))
+		}
+		if r.hasBestF1 {
+			p := toJsonPoint(r.bestF1)
+			jr.BestF1 = &p
+		}
+		if r.hasMinPrecision {
+			p := toJsonPoint(r.minPrecision)
+			jr.MinPrecision = &p
+		}
+		result.Reports = append(result.Reports, jr)
+	}
+
+	bytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, append(bytes, '\n'), 0o644)
+}
+
+func writeCsv(outPath string, reports []*report) error {
+	base := strings.TrimSuffix(outPath, csvExt)
+
+	summary := [][]string{{"scope", "purpose", "samples", "positives", "brier",
+		"best_f1_threshold", "best_f1", "min_precision_threshold", "min_precision_precision", "min_precision_recall"}}
+	reliability := [][]string{{"scope", "purpose", "lower", "upper", "n", "mean_score", "positive_rate"}}
+	points := [][]string{{"scope", "purpose", "threshold",
+		"true_positives", "false_positives", "false_negatives", "true_negatives", "precision", "recall", "f1"}}
+
+	for _, r := range reports {
+		row := []string{r.scope, r.purpose, strconv.Itoa(len(r.samples)), strconv.Itoa(r.positives), formatFloat(r.brier)}
+		if r.hasBestF1 {
+			row = append(row, formatFloat(r.bestF1.Threshold), formatFloat(r.bestF1.F1))
+		} else {
+			row = append(row, "", "")
+		}
+		if r.hasMinPrecision {
+			row = append(row, formatFloat(r.minPrecision.Threshold),
+				formatFloat(r.minPrecision.Precision), formatFloat(r.minPrecision.Recall))
+		} else {
+			row = append(row, "", "", "")
+		}
+		summary = append(summary, row)
+
+		for _, bin := range r.bins {
+			reliability = append(reliability, []string{r.scope, r.purpose,
+				formatFloat(bin.Lower), formatFloat(bin.Upper), strconv.Itoa(bin.N),
+				formatFloat(bin.MeanScore), formatFloat(bin.PositiveRate)})
+		}
+		for _, p := range r.points {
+			points = append(points, []string{r.scope, r.purpose, formatFloat(p.Threshold),
+				strconv.Itoa(p.TruePositives), strconv.Itoa(p.FalsePositives),
+				strconv.Itoa(p.FalseNegatives), strconv.Itoa(p.TrueNegatives),
+				formatFloat(p.Precision), formatFloat(p.Recall), formatFloat(p.F1)})
+		}
+	}
+
+	for path, records := range map[string][][]string{
+		outPath:                        summary,
+		base + ".reliability" + csvExt: reliability,
+		base + ".thresholds" + csvExt:  points,
+	} {
+		err := writeRecords(path, records)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeRecords(path string, records [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	writer := csv.NewWriter(file)
+	err = writer.WriteAll(records)
+	if err != nil {
+		return fmt.Errorf("writing %q: %w", path, err)
+	}
+	return nil
+}
@@ -3,14 +3,14 @@ package main
 import (
 	"bufio"
 	"compress/gzip"
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/apache/arrow/go/v18/arrow"
 	"github.com/apache/arrow/go/v18/arrow/array"
 	"github.com/apache/arrow/go/v18/arrow/memory"
-	"github.com/apache/arrow/go/v18/parquet"
-	"github.com/apache/arrow/go/v18/parquet/compress"
 	"github.com/apache/arrow/go/v18/parquet/pqarrow"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb"
@@ -18,17 +18,45 @@ import (
 	"github.com/willbeason/bondsmith/fileio"
 	"github.com/willbeason/bondsmith/jsonio"
 	"github.com/willbeason/bondsmith/statusbar"
+	"github.com/willbeason/software-mentions/pkg/normalize"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
+	"github.com/willbeason/software-mentions/pkg/subsets"
 	"github.com/willbeason/software-mentions/pkg/tables"
+	"github.com/willbeason/software-mentions/pkg/urls"
+	"github.com/willbeason/software-mentions/pkg/versions"
 	"golang.org/x/term"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"time"
 )
 
+const (
+	FlagLicenses      = "licenses"
+	FlagAliases       = "aliases"
+	FlagPurposeLayout = "purpose-layout"
+)
+
+const (
+	// purposeLayoutLong writes purpose assessments as rows of the
+	// purpose_assessments table, six per mention.
+	purposeLayoutLong = "long"
+	// purposeLayoutWide writes purpose assessments as columns of the mentions
+	// table.
+	purposeLayoutWide = "wide"
+)
+
 func main() {
+	cmd.Flags().String(FlagLicenses, "", "CSV file of license mappings overriding the defaults")
+	cmd.Flags().String(FlagAliases, "", "CSV file of software alias overrides extending the defaults")
+	cmd.Flags().String(FlagPurposeLayout, purposeLayoutLong,
+		"write purpose assessments as rows of the purpose_assessments table (long) or as columns of the mentions table (wide)")
+
 	err := cmd.Execute()
 	if err != nil {
 		os.Exit(1)
@@ -36,18 +64,94 @@ func main() {
 }
 
 var cmd = cobra.Command{
-	Use:     "extract-columns [papers|software|latex|jats|grobid|pub2tei] IN_DIR OUT_DIR",
-	Short:   "converts parts of the dataset into the Apache Parquet format",
+	Use:   "extract-columns [papers|software|latex|jats|grobid|pub2tei] IN_DIR OUT_DIR",
+	Short: "converts parts of the dataset into the Apache Parquet format",
+	Long: `converts parts of the dataset into the Apache Parquet format.
+
+Extracting mentions reads the input twice: once to count the software names so
+they can be clustered into the software_aliases table, and again to write the
+mentions with the canonical name of each. Each pass decompresses and parses the
+full input, so extracting mentions takes about twice as long as reading it.`,
 	Args:    cobra.ExactArgs(3),
 	Version: "0.1.0",
 	RunE:    runE,
 }
 
-func runE(_ *cobra.Command, args []string) error {
+func runE(cmd *cobra.Command, args []string) error {
 	extractType := args[0]
 	inPath := args[1]
 	outDir := args[2]
 
+	switch extractType {
+	case "papers":
+		licensesPath, err := cmd.Flags().GetString(FlagLicenses)
+		if err != nil {
+			return err
+		}
+		licenses, err := papers.LoadLicenseMapping(licensesPath)
+		if err != nil {
+			return err
+		}
+		return readInput(inPath, extractType, func(reader io.Reader, _ *fileio.MultiReader) error {
+			return extractPapers(reader, outDir, licenses)
+		})
+	default:
+		aliasesPath, err := cmd.Flags().GetString(FlagAliases)
+		if err != nil {
+			return err
+		}
+		purposeLayout, err := cmd.Flags().GetString(FlagPurposeLayout)
+		if err != nil {
+			return err
+		}
+		switch purposeLayout {
+		case purposeLayoutLong, purposeLayoutWide:
+		default:
+			return fmt.Errorf("unknown purpose layout %q, want %s or %s", purposeLayout, purposeLayoutLong, purposeLayoutWide)
+		}
+		overrides, err := normalize.LoadOverrides(aliasesPath)
+		if err != nil {
+			return err
+		}
+
+		// Clustering software names needs every name, so count them before
+		// extracting mentions.
+		clusterer := normalize.NewClusterer()
+		err = readInput(inPath, extractType, func(reader io.Reader, mr *fileio.MultiReader) error {
+			err := countSoftwareNames(reader, clusterer)
+			if err != nil {
+				return fmt.Errorf("counting %s software names in %q: %w", extractType, mr.CurFilepath(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		aliases := clusterer.Cluster(overrides)
+		err = writeAliases(aliases, extractType, outDir)
+		if err != nil {
+			return fmt.Errorf("writing %s software aliases: %w", extractType, err)
+		}
+
+		years, err := readPaperYears(cmd.Context(), outDir)
+		if err != nil {
+			return err
+		}
+
+		return readInput(inPath, extractType, func(reader io.Reader, mr *fileio.MultiReader) error {
+			err := extractMentions(reader, extractType, outDir, purposeLayout, aliases, years)
+			if err != nil {
+				return fmt.Errorf("extracting %s mentions from %q: %w", extractType, mr.CurFilepath(), err)
+			}
+			return nil
+		})
+	}
+}
+
+// readInput calls fn with the decompressed contents of the input files of
+// extractType, showing progress.
+func readInput(inPath, extractType string, fn func(reader io.Reader, mr *fileio.MultiReader) error) error {
 	inFile, err := os.Open(inPath)
 	if err != nil {
 		return err
@@ -94,16 +198,7 @@ func runE(_ *cobra.Command, args []string) error {
 	}()
 	defer ticker.Stop()
 
-	switch extractType {
-	case "papers":
-		return extractPapers(gzipReader, outDir)
-	default:
-		err := extractMentions(gzipReader, extractType, outDir)
-		if err != nil {
-			return fmt.Errorf("extracting %s mentions from %q: %w", extractType, mr.CurFilepath(), err)
-		}
-		return nil
-	}
+	return fn(gzipReader, mr)
 }
 
 // The "gg" here is for a special file for two papers which were missing metadata
@@ -209,14 +304,241 @@ type Name struct {
 	RawForm        string `json:"rawForm"`
 }
 
-func extractMentions(reader io.Reader, extractType, outDir string) error {
+// countSoftwareNames adds the name of each software mention to clusterer.
+func countSoftwareNames(reader io.Reader, clusterer *normalize.Clusterer) error {
+	softwareMentions := jsonio.NewReader(reader, func() *SoftwareMentions {
+		return &SoftwareMentions{}
+	})
+
+	for softwareMention, err := range softwareMentions.Read() {
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("reading software mentions: %w", err)
+		}
+
+		for _, mention := range softwareMention.Mentions {
+			clusterer.Add(mention.SoftwareName.NormalizedForm, mention.SoftwareName.WikidataId)
+		}
+	}
+
+	return nil
+}
+
+// writeAliases writes the software_aliases table of extractType.
+func writeAliases(aliases *normalize.Aliases, extractType, outDir string) error {
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.SoftwareAliases)
+	defer recordBuilder.Release()
+
+	fields := recordBuilder.Fields()
+	aliasField := fields[0].(*array.StringBuilder)
+	canonicalField := fields[1].(*array.StringBuilder)
+	idField := fields[2].(*array.Uint64Builder)
+	confidenceField := fields[3].(*array.Float64Builder)
+	ruleField := fields[4].(*array.BinaryDictionaryBuilder)
+	mentionsField := fields[5].(*array.Uint32Builder)
+
+	for _, alias := range aliases.List() {
+		aliasField.Append(alias.Alias)
+		canonicalField.Append(alias.Canonical)
+		idField.Append(normalize.SoftwareId(alias.Canonical))
+		confidenceField.Append(alias.Confidence)
+		err := ruleField.AppendString(string(alias.Rule))
+		if err != nil {
+			return fmt.Errorf("appending rule: %w", err)
+		}
+		mentionsField.Append(uint32(alias.Mentions))
+	}
+
+	return writeRecords(tables.SoftwareAliases, recordBuilder, outDir, tables.SoftwareAliasesName+"."+extractType)
+}
+
+// readPaperYears returns the published year of each paper in the papers table
+// in outDir, indexed by paper id, or unknownYear for papers without one.
+func readPaperYears(ctx context.Context, outDir string) ([]uint16, error) {
+	papersPath := filepath.Join(outDir, tables.PapersName+tables.ParquetExt)
+
+	var result []uint16
+	columns := []string{tables.PaperIdFieldName, tables.PublishedYearFieldName}
+	err := subsets.Scan(ctx, papersPath, columns, func(record arrow.Record) error {
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		yearColumn, err := subsets.Column[*array.Uint16](record, tables.PublishedYearFieldName)
+		if err != nil {
+			return err
+		}
+
+		for i, paperId := range paperIdColumn.Uint32Values() {
+			if yearColumn.IsNull(i) {
+				continue
+			}
+			if int(paperId) >= len(result) {
+				result = append(result, make([]uint16, int(paperId)+1-len(result))...)
+			}
+			result[paperId] = yearColumn.Value(i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading paper years; extract papers before mentions: %w", err)
+	}
+
+	return result, nil
+}
+
+const unknownYear = 0
+
+// softwareStats describes the mentions of a canonical software.
+type softwareStats struct {
+	canonical string
+	mentions  uint32
+	papers    uint32
+	// lastPaper is the last paper counted in papers. The mentions of a paper
+	// are consecutive, so this counts each paper once.
+	lastPaper uint32
+
+	firstYear uint16
+	lastYear  uint16
+
+	wikidata   map[string]int
+	publishers map[string]int
+	languages  map[string]int
+	urls       map[string]int
+}
+
+func newSoftwareStats(canonical string) *softwareStats {
+	return &softwareStats{
+		canonical:  canonical,
+		wikidata:   make(map[string]int),
+		publishers: make(map[string]int),
+		languages:  make(map[string]int),
+		urls:       make(map[string]int),
+	}
+}
+
+func (s *softwareStats) add(paperId uint32, year uint16, mention *SoftwareMention) {
+	s.mentions++
+	if s.papers == 0 || s.lastPaper != paperId {
+		s.papers++
+		s.lastPaper = paperId
+	}
+
+	if year != unknownYear {
+		if s.firstYear == unknownYear || year < s.firstYear {
+			s.firstYear = year
+		}
+		if year > s.lastYear {
+			s.lastYear = year
+		}
+	}
+
+	if mention.SoftwareName.WikidataId != "" {
+		s.wikidata[mention.SoftwareName.WikidataId]++
+	}
+	for _, counted := range []struct {
+		counts map[string]int
+		name   Name
+	}{
+		{s.publishers, mention.Publisher},
+		{s.languages, mention.Language},
+		{s.urls, mention.URL},
+	} {
+		value := counted.name.NormalizedForm
+		if value == "" {
+			value = counted.name.RawForm
+		}
+		if value != "" {
+			counted.counts[value]++
+		}
+	}
+}
+
+// mostCommon returns the value with the largest count, the least such value if
+// there are several, or false if counts is empty.
+func mostCommon(counts map[string]int) (string, bool) {
+	result := ""
+	count := 0
+	for value, c := range counts {
+		if c > count || c == count && value < result {
+			result = value
+			count = c
+		}
+	}
+	return result, count > 0
+}
+
+// writeSoftware writes the software table of extractType.
+func writeSoftware(software map[string]*softwareStats, extractType, outDir string) error {
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.Software)
+	defer recordBuilder.Release()
+
+	fields := recordBuilder.Fields()
+	idField := fields[0].(*array.Uint64Builder)
+	canonicalField := fields[1].(*array.StringBuilder)
+	wikidataField := fields[2].(*array.StringBuilder)
+	publisherField := fields[3].(*array.StringBuilder)
+	languageField := fields[4].(*array.StringBuilder)
+	urlField := fields[5].(*array.StringBuilder)
+	firstYearField := fields[6].(*array.Uint16Builder)
+	lastYearField := fields[7].(*array.Uint16Builder)
+	mentionsField := fields[8].(*array.Uint32Builder)
+	papersField := fields[9].(*array.Uint32Builder)
+
+	canonicals := make([]string, 0, len(software))
+	for canonical := range software {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+
+	for _, canonical := range canonicals {
+		stats := software[canonical]
+
+		idField.Append(normalize.SoftwareId(canonical))
+		canonicalField.Append(canonical)
+		for _, counted := range []struct {
+			counts map[string]int
+			field  *array.StringBuilder
+		}{
+			{stats.wikidata, wikidataField},
+			{stats.publishers, publisherField},
+			{stats.languages, languageField},
+			{stats.urls, urlField},
+		} {
+			if value, found := mostCommon(counted.counts); found {
+				counted.field.Append(value)
+			} else {
+				counted.field.AppendNull()
+			}
+		}
+		if stats.firstYear == unknownYear {
+			firstYearField.AppendNull()
+			lastYearField.AppendNull()
+		} else {
+			firstYearField.Append(stats.firstYear)
+			lastYearField.Append(stats.lastYear)
+		}
+		mentionsField.Append(stats.mentions)
+		papersField.Append(stats.papers)
+	}
+
+	return writeRecords(tables.Software, recordBuilder, outDir, tables.SoftwareName+"."+extractType)
+}
+
+func extractMentions(reader io.Reader, extractType, outDir, purposeLayout string, aliases *normalize.Aliases, years []uint16) error {
 	softwareMentions := jsonio.NewReader(reader, func() *SoftwareMentions {
 		return &SoftwareMentions{}
 	})
 
 	allocator := memory.NewGoAllocator()
 
-	softwareMentionsRecordBuilder := array.NewRecordBuilder(allocator, tables.SoftwareMentions)
+	mentionsSchema := tables.SoftwareMentions
+	if purposeLayout == purposeLayoutWide {
+		mentionsSchema = tables.SoftwareMentionsWide
+	}
+
+	softwareMentionsRecordBuilder := array.NewRecordBuilder(allocator, mentionsSchema)
 	defer softwareMentionsRecordBuilder.Release()
 
 	softwareMentionsFields := softwareMentionsRecordBuilder.Fields()
@@ -235,6 +557,18 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 	softwareMentionUrlRawField := softwareMentionsFields[12].(*array.StringBuilder)
 	softwareMentionUrlNormalizedField := softwareMentionsFields[13].(*array.StringBuilder)
 	softwareMentionContextField := softwareMentionsFields[14].(*array.StringBuilder)
+	softwareMentionWikidataField := softwareMentionsFields[15].(*array.StringBuilder)
+	softwareMentionCanonicalField := softwareMentionsFields[16].(*array.StringBuilder)
+	softwareMentionSoftwareIdField := softwareMentionsFields[17].(*array.Uint64Builder)
+	softwareMentionVersionMajorField := softwareMentionsFields[18].(*array.Uint32Builder)
+	softwareMentionVersionMinorField := softwareMentionsFields[19].(*array.Uint32Builder)
+	softwareMentionVersionPatchField := softwareMentionsFields[20].(*array.Uint32Builder)
+	softwareMentionVersionPrereleaseField := softwareMentionsFields[21].(*array.StringBuilder)
+	softwareMentionVersionReleaseField := softwareMentionsFields[22].(*array.StringBuilder)
+	softwareMentionVersionSortKeyField := softwareMentionsFields[23].(*array.StringBuilder)
+	// In the wide layout, the certainty score and value of each purpose and
+	// scope, ordered as in tables.SoftwareMentionsWide.
+	softwareMentionPurposeFields := softwareMentionsFields[len(tables.SoftwareMentions.Fields()):]
 
 	purposeAssessmentRecordBuilder := array.NewRecordBuilder(allocator, tables.PurposeAssessment)
 	defer purposeAssessmentRecordBuilder.Release()
@@ -247,21 +581,14 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 	purposeAssessmentScopeField := purposeAssessmentFields[4].(*array.BinaryDictionaryBuilder)
 	purposeAssessmentPurposeField := purposeAssessmentFields[5].(*array.BinaryDictionaryBuilder)
 	purposeAssessmentCertaintyField := purposeAssessmentFields[6].(*array.Float64Builder)
+	purposeAssessmentDecisionField := purposeAssessmentFields[7].(*array.BooleanBuilder)
+
+	mentionUrlsRecordBuilder := array.NewRecordBuilder(allocator, tables.MentionUrls)
+	defer mentionUrlsRecordBuilder.Release()
 
 	// Mention writer logic
 	mentionsPath := filepath.Join(outDir, tables.MentionsName+"."+extractType+tables.ParquetExt)
-	mentionsFile, err := os.Create(mentionsPath)
-	if err != nil {
-		return fmt.Errorf("creating mentions file: %w", err)
-	}
-	// Don't close mentionsFile; parquet handles closing it.
-	mentionsWriter, err := pqarrow.NewFileWriter(
-		tables.SoftwareMentions,
-		mentionsFile,
-		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Gzip),
-			parquet.WithCompressionLevel(gzip.BestCompression)),
-		pqarrow.DefaultWriterProps(),
-	)
+	mentionsWriter, err := subsets.NewWriter(mentionsSchema, mentionsPath)
 	if err != nil {
 		return fmt.Errorf("creating mentions writer: %w", err)
 	}
@@ -274,25 +601,31 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 	}()
 
 	// Purpose writer logic
-	purposePath := filepath.Join(outDir, tables.PurposeAssessmentsName+"."+extractType+tables.ParquetExt)
-	purposeFile, err := os.Create(purposePath)
-	if err != nil {
-		return fmt.Errorf("creating purpose file: %w", err)
-	}
-	// Don't close outFile; parquet handles closing it.
-	purposeWriter, err := pqarrow.NewFileWriter(
-		tables.PurposeAssessment,
-		purposeFile,
-		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Gzip),
-			parquet.WithCompressionLevel(gzip.BestCompression)),
-		pqarrow.DefaultWriterProps(),
-	)
+	var purposeWriter *pqarrow.FileWriter
+	if purposeLayout == purposeLayoutLong {
+		purposePath := filepath.Join(outDir, tables.PurposeAssessmentsName+"."+extractType+tables.ParquetExt)
+		purposeWriter, err = subsets.NewWriter(tables.PurposeAssessment, purposePath)
+		if err != nil {
+			return fmt.Errorf("creating purpose writer: %w", err)
+		}
+
+		defer func() {
+			err := purposeWriter.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}()
+	}
+
+	// Mention URL writer logic
+	mentionUrlsPath := filepath.Join(outDir, tables.MentionUrlsName+"."+extractType+tables.ParquetExt)
+	mentionUrlsWriter, err := subsets.NewWriter(tables.MentionUrls, mentionUrlsPath)
 	if err != nil {
-		return fmt.Errorf("creating purpose writer: %w", err)
+		return fmt.Errorf("creating mention urls writer: %w", err)
 	}
 
 	defer func() {
-		err := purposeWriter.Close()
+		err := mentionUrlsWriter.Close()
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -343,6 +676,9 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 		}
 	}()
 
+	software := make(map[string]*softwareStats)
+	consistencies := make(map[[2]string]*decisionConsistency)
+
 	// Loop
 	i := 0
 	for softwareMention, err := range softwareMentions.Read() {
@@ -356,12 +692,21 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 			}
 			mentionRecord.Release()
 
-			purposeRecord := purposeAssessmentRecordBuilder.NewRecord()
-			errWrite = purposeWriter.Write(purposeRecord)
+			if purposeWriter != nil {
+				purposeRecord := purposeAssessmentRecordBuilder.NewRecord()
+				errWrite = purposeWriter.Write(purposeRecord)
+				if errWrite != nil {
+					return fmt.Errorf("writing purpose: %w", errWrite)
+				}
+				purposeRecord.Release()
+			}
+
+			mentionUrlsRecord := mentionUrlsRecordBuilder.NewRecord()
+			errWrite = mentionUrlsWriter.Write(mentionUrlsRecord)
 			if errWrite != nil {
-				return fmt.Errorf("writing purpose: %w", errWrite)
+				return fmt.Errorf("writing mention urls: %w", errWrite)
 			}
-			purposeRecord.Release()
+			mentionUrlsRecord.Release()
 		}
 
 		if errors.Is(err, io.EOF) {
@@ -453,12 +798,71 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 				softwareMentionUrlNormalizedField.Append(mention.URL.NormalizedForm)
 			}
 
+			if mention.URL.RawForm != "" || mention.URL.NormalizedForm != "" {
+				err = appendMentionUrl(mentionUrlsRecordBuilder, softwareMentionId, paperId, mention.URL)
+				if err != nil {
+					return fmt.Errorf("appending mention url: %w", err)
+				}
+			}
+
 			if mention.Context == "" {
 				softwareMentionContextField.AppendNull()
 			} else {
 				softwareMentionContextField.Append(mention.Context)
 			}
 
+			if mention.SoftwareName.WikidataId == "" {
+				softwareMentionWikidataField.AppendNull()
+			} else {
+				softwareMentionWikidataField.Append(mention.SoftwareName.WikidataId)
+			}
+			canonical := aliases.Canonical(mention.SoftwareName.NormalizedForm)
+			softwareMentionCanonicalField.Append(canonical)
+			softwareMentionSoftwareIdField.Append(normalize.SoftwareId(canonical))
+
+			versionString := mention.Version.NormalizedForm
+			if versionString == "" {
+				versionString = mention.Version.RawForm
+			}
+			version, hasVersion := versions.Parse(versionString)
+			for component, field := range []*array.Uint32Builder{
+				softwareMentionVersionMajorField,
+				softwareMentionVersionMinorField,
+				softwareMentionVersionPatchField,
+			} {
+				if !hasVersion || component >= version.Components {
+					field.AppendNull()
+					continue
+				}
+				field.Append(uint32([]int{version.Major, version.Minor, version.Patch}[component]))
+			}
+			if version.Prerelease == "" {
+				softwareMentionVersionPrereleaseField.AppendNull()
+			} else {
+				softwareMentionVersionPrereleaseField.Append(version.Prerelease)
+			}
+			if version.Release == "" {
+				softwareMentionVersionReleaseField.AppendNull()
+			} else {
+				softwareMentionVersionReleaseField.Append(version.Release)
+			}
+			if hasVersion {
+				softwareMentionVersionSortKeyField.Append(version.SortKey())
+			} else {
+				softwareMentionVersionSortKeyField.AppendNull()
+			}
+
+			stats, found := software[canonical]
+			if !found {
+				stats = newSoftwareStats(canonical)
+				software[canonical] = stats
+			}
+			year := uint16(unknownYear)
+			if int(paperId) < len(years) {
+				year = years[paperId]
+			}
+			stats.add(paperId, year, &mention)
+
 			// Purpose Assessments
 			for _, scope := range []string{"document", "local"} {
 				var contextAttributes ContextAttributes
@@ -484,6 +888,20 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 						panic("invalid purpose " + purpose)
 					}
 
+					consistency, found := consistencies[[2]string{scope, purpose}]
+					if !found {
+						consistency = &decisionConsistency{}
+						consistencies[[2]string{scope, purpose}] = consistency
+					}
+					consistency.add(purposeScoreValue)
+
+					if purposeLayout == purposeLayoutWide {
+						column := 2 * (slices.Index(tables.Purposes, purpose)*len(tables.Scopes) + slices.Index(tables.Scopes, scope))
+						softwareMentionPurposeFields[column].(*array.Float32Builder).Append(float32(purposeScoreValue.Score))
+						softwareMentionPurposeFields[column+1].(*array.BooleanBuilder).Append(purposeScoreValue.Value)
+						continue
+					}
+
 					//// Purpose Assessment
 					purposeAssessmentIdField.Append(softwareMentionId)
 					purposeAssessmentPaperIdField.Append(uint32(paperId))
@@ -501,27 +919,248 @@ func extractMentions(reader io.Reader, extractType, outDir string) error {
 						return fmt.Errorf("appending purpose: %w", err)
 					}
 					purposeAssessmentCertaintyField.Append(purposeScoreValue.Score)
+					purposeAssessmentDecisionField.Append(purposeScoreValue.Value)
 				}
 			}
 		}
 	}
 
+	err = writeSoftware(software, extractType, outDir)
+	if err != nil {
+		return fmt.Errorf("writing software: %w", err)
+	}
+
+	printDecisionConsistency(consistencies)
+
+	return nil
+}
+
+// decisionThreshold is the certainty score above which a purpose decision is
+// expected to be true, as in the usual certainty_score > 0.5.
+const decisionThreshold = 0.5
+
+// decisionConsistency counts the purpose assessments of a scope and purpose
+// whose decision disagrees with their certainty score at decisionThreshold.
+type decisionConsistency struct {
+	trues  int
+	falses int
+	// trueAtOrBelow are assessments decided true with a score at or below the
+	// threshold.
+	trueAtOrBelow int
+	// falseAbove are assessments decided false with a score above the
+	// threshold.
+	falseAbove int
+	// minTrue and maxFalse bound the threshold the model actually used.
+	minTrue  float64
+	maxFalse float64
+}
+
+func (c *decisionConsistency) add(scoreValue ScoreValue) {
+	score := scoreValue.Score
+	if scoreValue.Value {
+		if c.trues == 0 || score < c.minTrue {
+			c.minTrue = score
+		}
+		if score <= decisionThreshold {
+			c.trueAtOrBelow++
+		}
+		c.trues++
+	} else {
+		if c.falses == 0 || score > c.maxFalse {
+			c.maxFalse = score
+		}
+		if score > decisionThreshold {
+			c.falseAbove++
+		}
+		c.falses++
+	}
+}
+
+// printDecisionConsistency reports, for each scope and purpose, the number of
+// assessments, how many have a decision disagreeing with their certainty score
+// at decisionThreshold, and the smallest score decided true and largest
+// decided false. If the model used a single other cutoff, it lies between
+// those two scores.
+func printDecisionConsistency(consistencies map[[2]string]*decisionConsistency) {
+	formatScore := func(score float64, n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+
+	fmt.Println()
+	fmt.Println("scope;purpose;assessments;true_at_or_below_threshold;false_above_threshold;min_true_score;max_false_score")
+	for _, scope := range tables.Scopes {
+		for _, purpose := range tables.Purposes {
+			c, found := consistencies[[2]string{scope, purpose}]
+			if !found {
+				continue
+			}
+			fmt.Printf("%s;%s;%d;%d;%d;%s;%s\n", scope, purpose, c.trues+c.falses, c.trueAtOrBelow, c.falseAbove,
+				formatScore(c.minTrue, c.trues), formatScore(c.maxFalse, c.falses))
+		}
+	}
+}
+
+// appendMentionUrl appends the classified URL of a mention to the mention_urls
+// recordBuilder. URLs which can't be parsed are appended with only their
+// mention and text.
+func appendMentionUrl(recordBuilder *array.RecordBuilder, softwareMentionId string, paperId uint32, name Name) error {
+	fields := recordBuilder.Fields()
+	softwareMentionIdField := fields[0].(*array.StringBuilder)
+	paperIdField := fields[1].(*array.Uint32Builder)
+	urlField := fields[2].(*array.StringBuilder)
+	canonicalField := fields[3].(*array.StringBuilder)
+	hostField := fields[4].(*array.StringBuilder)
+	kindField := fields[5].(*array.BinaryDictionaryBuilder)
+	ownerField := fields[6].(*array.StringBuilder)
+	nameField := fields[7].(*array.StringBuilder)
+	repositoryField := fields[8].(*array.StringBuilder)
+	doiField := fields[9].(*array.StringBuilder)
+
+	rawUrl := name.NormalizedForm
+	if rawUrl == "" {
+		rawUrl = name.RawForm
+	}
+
+	softwareMentionIdField.Append(softwareMentionId)
+	paperIdField.Append(paperId)
+	urlField.Append(rawUrl)
+
+	parsed, err := urls.Parse(rawUrl)
+	if err != nil {
+		canonicalField.AppendNull()
+		hostField.AppendNull()
+		kindField.AppendNull()
+		ownerField.AppendNull()
+		nameField.AppendNull()
+		repositoryField.AppendNull()
+		doiField.AppendNull()
+		return nil
+	}
+
+	canonicalField.Append(parsed.Canonical)
+	hostField.Append(parsed.Host)
+	err = kindField.AppendString(string(parsed.Kind))
+	if err != nil {
+		return fmt.Errorf("appending kind: %w", err)
+	}
+	for _, column := range []struct {
+		value string
+		field *array.StringBuilder
+	}{
+		{parsed.Owner, ownerField},
+		{parsed.Name, nameField},
+		{parsed.Repository, repositoryField},
+		{parsed.Doi, doiField},
+	} {
+		if column.value == "" {
+			column.field.AppendNull()
+		} else {
+			column.field.Append(column.value)
+		}
+	}
+
 	return nil
 }
 
 type Paper struct {
-	ID            string `json:"id"`
-	File          string `json:"file"`
-	Title         string `json:"title"`
-	PublishedYear int    `json:"year"`
-	PublishedDate string `json:"published_date"`
-	JournalName   string `json:"journal_name"`
-	PublisherName string `json:"publisher"`
-	DOI           string `json:"doi"`
-	PMCID         string `json:"pmcid"`
-	PMID          string `json:"pmid"`
-	Genre         string `json:"genre"`
-	LicenseType   string `json:"license"`
+	ID            string  `json:"id"`
+	File          string  `json:"file"`
+	Title         string  `json:"title"`
+	PublishedYear int     `json:"year"`
+	PublishedDate string  `json:"published_date"`
+	JournalName   string  `json:"journal_name"`
+	PublisherName string  `json:"publisher"`
+	DOI           string  `json:"doi"`
+	PMCID         string  `json:"pmcid"`
+	PMID          string  `json:"pmid"`
+	Genre         string  `json:"genre"`
+	LicenseType   string  `json:"license"`
+	ZAuthors      Authors `json:"z_authors"`
+	Glutton       Glutton `json:"glutton"`
+
+	OaStatus          string               `json:"oa_status"`
+	JournalIsOa       *bool                `json:"journal_is_oa"`
+	HasRepositoryCopy *bool                `json:"has_repository_copy"`
+	OaLocations       []OpenAccessLocation `json:"oa_locations"`
+	BestOaLocation    *OpenAccessLocation  `json:"best_oa_location"`
+}
+
+type OpenAccessLocation struct {
+	URL                   string `json:"url"`
+	PmhId                 string `json:"pmh_id"`
+	IsBest                bool   `json:"is_best"`
+	License               string `json:"license"`
+	OaDate                string `json:"oa_date"`
+	Version               string `json:"version"`
+	Evidence              string `json:"evidence"`
+	HostType              string `json:"host_type"`
+	EndpointId            string `json:"endpoint_id"`
+	URLForPdf             string `json:"url_for_pdf"`
+	URLForLandingPage     string `json:"url_for_landing_page"`
+	RepositoryInstitution string `json:"repository_institution"`
+}
+
+type Glutton struct {
+	Author Authors `json:"author"`
+}
+
+type Author struct {
+	Given       string       `json:"given"`
+	Family      string       `json:"family"`
+	Sequence    string       `json:"sequence"`
+	Affiliation Affiliations `json:"affiliation"`
+}
+
+// Authors are a list of authors. Glutton metadata sometimes has a single author
+// rather than a list.
+type Authors []Author
+
+func (a *Authors) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var author Author
+		err := json.Unmarshal(data, &author)
+		if err != nil {
+			return err
+		}
+		*a = Authors{author}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]Author)(a))
+}
+
+// Affiliations are the names of an author's affiliations, which are written
+// either as strings or as objects with a name.
+type Affiliations []string
+
+func (a *Affiliations) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	err := json.Unmarshal(data, &raws)
+	if err != nil {
+		return err
+	}
+
+	*a = nil
+	for _, raw := range raws {
+		var affiliation struct {
+			Name string `json:"name"`
+		}
+		if len(raw) > 0 && raw[0] == '"' {
+			err = json.Unmarshal(raw, &affiliation.Name)
+		} else {
+			err = json.Unmarshal(raw, &affiliation)
+		}
+		if err != nil {
+			return err
+		}
+		if affiliation.Name != "" {
+			*a = append(*a, affiliation.Name)
+		}
+	}
+	return nil
 }
 
 const (
@@ -529,8 +1168,8 @@ const (
 	hasMentionsFileName = "has_mentions.csv"
 )
 
-func extractPapers(reader io.Reader, outDir string) error {
-	papers := jsonio.NewReader(reader, func() *Paper {
+func extractPapers(reader io.Reader, outDir string, licenses *papers.LicenseMapping) error {
+	paperEntries := jsonio.NewReader(reader, func() *Paper {
 		return &Paper{}
 	})
 
@@ -553,7 +1192,19 @@ func extractPapers(reader io.Reader, outDir string) error {
 	pmidField := paperFields[9].(*array.StringBuilder)
 	genreField := paperFields[10].(*array.BinaryDictionaryBuilder)
 	licenseTypeField := paperFields[11].(*array.BinaryDictionaryBuilder)
-	hasMentionsField := paperFields[12].(*array.BooleanBuilder)
+	licenseSpdxField := paperFields[12].(*array.BinaryDictionaryBuilder)
+	hasMentionsField := paperFields[13].(*array.BooleanBuilder)
+	oaStatusField := paperFields[14].(*array.BinaryDictionaryBuilder)
+	journalIsOaField := paperFields[15].(*array.BooleanBuilder)
+	hasRepositoryCopyField := paperFields[16].(*array.BooleanBuilder)
+
+	paperAuthorsRecordBuilder := array.NewRecordBuilder(allocator, tables.PaperAuthors)
+	defer paperAuthorsRecordBuilder.Release()
+
+	oaLocationsRecordBuilder := array.NewRecordBuilder(allocator, tables.OaLocations)
+	defer oaLocationsRecordBuilder.Release()
+
+	authors := newAuthorIndex()
 
 	hasMentionsPath := filepath.Join(outDir, hasMentionsFileName)
 	var hasMentionsMap map[string]struct{}
@@ -591,8 +1242,11 @@ func extractPapers(reader io.Reader, outDir string) error {
 	}()
 	paperIdsWriter := csv.NewWriter(paperIdsFile)
 
+	outcomes := &ids.Outcomes{}
+	unknownLicenses := make(map[string]int)
+
 	paperId := uint32(0)
-	for paper, err := range papers.Read() {
+	for paper, err := range paperEntries.Read() {
 		if err != nil {
 			if !errors.Is(err, io.EOF) {
 				return err
@@ -648,18 +1302,20 @@ func extractPapers(reader io.Reader, outDir string) error {
 			publisherNameField.Append(paper.PublisherName)
 		}
 
-		doiField.Append(paper.DOI)
+		doiField.Append(normalizeId(outcomes, ids.KindDoi, paper.DOI))
 
-		if paper.PMCID == "" {
+		pmcid := normalizeId(outcomes, ids.KindPmcid, paper.PMCID)
+		if pmcid == "" {
 			pmcidField.AppendNull()
 		} else {
-			pmcidField.Append(paper.PMCID)
+			pmcidField.Append(pmcid)
 		}
 
-		if paper.PMID == "" {
+		pmid := normalizeId(outcomes, ids.KindPmid, paper.PMID)
+		if pmid == "" {
 			pmidField.AppendNull()
 		} else {
-			pmidField.Append(paper.PMID)
+			pmidField.Append(pmid)
 		}
 
 		if paper.Genre == "" {
@@ -673,11 +1329,17 @@ func extractPapers(reader io.Reader, outDir string) error {
 
 		if paper.LicenseType == "" {
 			licenseTypeField.AppendNull()
+			licenseSpdxField.AppendNull()
 		} else {
 			err = licenseTypeField.AppendString(paper.LicenseType)
 			if err != nil {
 				return err
 			}
+
+			err = licenseSpdxField.AppendString(licenseSpdx(licenses, paper.LicenseType, unknownLicenses))
+			if err != nil {
+				return err
+			}
 		}
 
 		if _, exists := hasMentionsMap[softciteId]; exists {
@@ -685,6 +1347,37 @@ func extractPapers(reader io.Reader, outDir string) error {
 		} else {
 			hasMentionsField.Append(false)
 		}
+
+		if paper.OaStatus == "" {
+			oaStatusField.AppendNull()
+		} else {
+			err = oaStatusField.AppendString(paper.OaStatus)
+			if err != nil {
+				return err
+			}
+		}
+
+		if paper.JournalIsOa == nil {
+			journalIsOaField.AppendNull()
+		} else {
+			journalIsOaField.Append(*paper.JournalIsOa)
+		}
+
+		if paper.HasRepositoryCopy == nil {
+			hasRepositoryCopyField.AppendNull()
+		} else {
+			hasRepositoryCopyField.Append(*paper.HasRepositoryCopy)
+		}
+
+		err = appendOaLocations(oaLocationsRecordBuilder, paperId, paper)
+		if err != nil {
+			return err
+		}
+
+		err = appendPaperAuthors(paperAuthorsRecordBuilder, authors, paperId, paper)
+		if err != nil {
+			return err
+		}
 	}
 
 	paperIdsWriter.Flush()
@@ -692,36 +1385,280 @@ func extractPapers(reader io.Reader, outDir string) error {
 		return fmt.Errorf("flushing paper ids: %w", paperIdsWriter.Error())
 	}
 
+	// Add newline to prevent the first line of output from being consumed by progress bar.
+	fmt.Println()
+	err = outcomes.WriteTable(os.Stdout)
+	if err != nil {
+		return fmt.Errorf("writing identifier normalization outcomes: %w", err)
+	}
+	printUnknownLicenses(unknownLicenses)
+
+	err = writeAuthors(authors, outDir)
+	if err != nil {
+		return fmt.Errorf("writing authors: %w", err)
+	}
+	err = writeRecords(tables.PaperAuthors, paperAuthorsRecordBuilder, outDir, tables.PaperAuthorsName)
+	if err != nil {
+		return fmt.Errorf("writing paper authors: %w", err)
+	}
+	err = writeRecords(tables.OaLocations, oaLocationsRecordBuilder, outDir, tables.OaLocationsName)
+	if err != nil {
+		return fmt.Errorf("writing open access locations: %w", err)
+	}
+
 	return writeRecords(schema, paperRecordBuilder, outDir, tables.PapersName)
 }
 
-func writeRecords(schema *arrow.Schema, recordBuilder *array.RecordBuilder, outDir, outTable string) error {
-	outPath := filepath.Join(outDir, outTable+tables.ParquetExt)
-	outFile, err := os.Create(outPath)
-	if err != nil {
-		return err
+// authorIndex assigns ids to authors by name and first affiliation, so that
+// authors who share a name at different institutions have different ids.
+type authorIndex struct {
+	ids     map[string]uint32
+	authors []Author
+	papers  []uint32
+	// lastPaper is the last paper each author was counted in, so authors listed
+	// twice on a paper are counted once. Paper ids start at 1.
+	lastPaper []uint32
+}
+
+func newAuthorIndex() *authorIndex {
+	return &authorIndex{ids: make(map[string]uint32)}
+}
+
+// add returns the id of author, counting paperId as one of their papers, or
+// false if author has no name. Ids start at 1.
+func (a *authorIndex) add(paperId uint32, author Author) (uint32, bool) {
+	name := normalize.Key(author.Given + " " + author.Family)
+	if name == "" {
+		return 0, false
+	}
+	key := name
+	if len(author.Affiliation) > 0 {
+		key += "\x00" + normalize.Key(author.Affiliation[0])
+	}
+
+	i, found := a.ids[key]
+	if !found {
+		i = uint32(len(a.authors))
+		a.ids[key] = i
+		a.authors = append(a.authors, author)
+		a.papers = append(a.papers, 0)
+		a.lastPaper = append(a.lastPaper, 0)
+	}
+	if a.lastPaper[i] != paperId {
+		a.lastPaper[i] = paperId
+		a.papers[i]++
+	}
+
+	return i + 1, true
+}
+
+// appendPaperAuthors appends the authors of a paper in the order they are
+// listed, adding them to authors. Authors come from z_authors, or from Glutton
+// metadata if there are none. Authors without a name are skipped, but keep
+// their place in the positions of the others.
+func appendPaperAuthors(recordBuilder *array.RecordBuilder, authors *authorIndex, paperId uint32, paper *Paper) error {
+	fields := recordBuilder.Fields()
+	paperIdField := fields[0].(*array.Uint32Builder)
+	authorIdField := fields[1].(*array.Uint32Builder)
+	positionField := fields[2].(*array.Uint16Builder)
+	sequenceField := fields[3].(*array.BinaryDictionaryBuilder)
+	affiliationsField := fields[4].(*array.ListBuilder)
+	affiliationField := affiliationsField.ValueBuilder().(*array.StringBuilder)
+	sourceField := fields[5].(*array.BinaryDictionaryBuilder)
+
+	paperAuthors, source := paper.ZAuthors, "z_authors"
+	if len(paperAuthors) == 0 {
+		paperAuthors, source = paper.Glutton.Author, "glutton"
+	}
+	for position, author := range paperAuthors {
+		authorId, found := authors.add(paperId, author)
+		if !found {
+			continue
+		}
+
+		paperIdField.Append(paperId)
+		authorIdField.Append(authorId)
+		positionField.Append(uint16(position))
+		if author.Sequence == "" {
+			sequenceField.AppendNull()
+		} else {
+			err := sequenceField.AppendString(author.Sequence)
+			if err != nil {
+				return err
+			}
+		}
+		affiliationsField.Append(true)
+		for _, affiliation := range author.Affiliation {
+			affiliationField.Append(affiliation)
+		}
+		err := sourceField.AppendString(source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeAuthors(authors *authorIndex, outDir string) error {
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.Authors)
+	defer recordBuilder.Release()
+
+	appendAuthors(recordBuilder, authors)
+
+	return writeRecords(tables.Authors, recordBuilder, outDir, tables.AuthorsName)
+}
+
+// appendAuthors appends the authors in order of their ids.
+func appendAuthors(recordBuilder *array.RecordBuilder, authors *authorIndex) {
+	fields := recordBuilder.Fields()
+	authorIdField := fields[0].(*array.Uint32Builder)
+	givenField := fields[1].(*array.StringBuilder)
+	familyField := fields[2].(*array.StringBuilder)
+	papersField := fields[3].(*array.Uint32Builder)
+
+	for i, author := range authors.authors {
+		authorIdField.Append(uint32(i + 1))
+		if author.Given == "" {
+			givenField.AppendNull()
+		} else {
+			givenField.Append(author.Given)
+		}
+		if author.Family == "" {
+			familyField.AppendNull()
+		} else {
+			familyField.Append(author.Family)
+		}
+		papersField.Append(authors.papers[i])
+	}
+}
+
+// licenseSpdx returns the SPDX id of the raw license string, or
+// papers.UnknownLicense if it is unmapped, counting it in unknownLicenses.
+func licenseSpdx(licenses *papers.LicenseMapping, raw string, unknownLicenses map[string]int) string {
+	license, found := licenses.Lookup(raw)
+	if !found {
+		unknownLicenses[raw]++
+		return papers.UnknownLicense
+	}
+	return license.Spdx
+}
+
+// printUnknownLicenses reports the license strings missing from the mapping,
+// most common first.
+func printUnknownLicenses(unknownLicenses map[string]int) {
+	if len(unknownLicenses) == 0 {
+		return
+	}
+
+	raws := make([]string, 0, len(unknownLicenses))
+	for raw := range unknownLicenses {
+		raws = append(raws, raw)
 	}
-	// Don't close outFile; parquet handles closing it.
-	writer, err := pqarrow.NewFileWriter(
-		schema,
-		outFile,
-		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Gzip),
-			parquet.WithCompressionLevel(gzip.BestCompression)),
-		pqarrow.DefaultWriterProps(),
-	)
+	sort.Slice(raws, func(i, j int) bool {
+		return unknownLicenses[raws[i]] > unknownLicenses[raws[j]]
+	})
+
+	fmt.Println()
+	for _, raw := range raws {
+		fmt.Printf("%s;%s;%d\n", papers.UnknownLicense, raw, unknownLicenses[raw])
+	}
+}
+
+// normalizeId returns the canonical form of an identifier. Invalid identifiers
+// are kept verbatim rather than discarded.
+func normalizeId(outcomes *ids.Outcomes, kind ids.Kind, raw string) string {
+	normalized, err := outcomes.Normalize(kind, raw)
 	if err != nil {
-		return err
+		return raw
 	}
+	return normalized
+}
 
-	defer func() {
-		err := writer.Close()
+// appendOaLocations appends the open access locations of a paper, skipping
+// those without a URL.
+func appendOaLocations(recordBuilder *array.RecordBuilder, paperId uint32, paper *Paper) error {
+	fields := recordBuilder.Fields()
+	paperIdField := fields[0].(*array.Uint32Builder)
+	locationIndexField := fields[1].(*array.Uint16Builder)
+	isBestField := fields[2].(*array.BooleanBuilder)
+	urlField := fields[3].(*array.StringBuilder)
+	urlForPdfField := fields[4].(*array.StringBuilder)
+	urlForLandingPageField := fields[5].(*array.StringBuilder)
+	hostTypeField := fields[6].(*array.BinaryDictionaryBuilder)
+	versionField := fields[7].(*array.BinaryDictionaryBuilder)
+	licenseField := fields[8].(*array.BinaryDictionaryBuilder)
+	evidenceField := fields[9].(*array.BinaryDictionaryBuilder)
+	repositoryInstitutionField := fields[10].(*array.StringBuilder)
+	pmhIdField := fields[11].(*array.StringBuilder)
+	endpointIdField := fields[12].(*array.StringBuilder)
+	oaDateField := fields[13].(*array.Date32Builder)
+
+	appendString := func(field *array.StringBuilder, value string) {
+		if value == "" {
+			field.AppendNull()
+		} else {
+			field.Append(value)
+		}
+	}
+	appendDictionary := func(field *array.BinaryDictionaryBuilder, value string) error {
+		if value == "" {
+			field.AppendNull()
+			return nil
+		}
+		return field.AppendString(value)
+	}
+
+	for i, location := range paper.OaLocations {
+		if location.URL == "" {
+			continue
+		}
+
+		paperIdField.Append(paperId)
+		locationIndexField.Append(uint16(i))
+		isBestField.Append(isBestOaLocation(location, paper.BestOaLocation))
+		urlField.Append(location.URL)
+		appendString(urlForPdfField, location.URLForPdf)
+		appendString(urlForLandingPageField, location.URLForLandingPage)
+
+		for _, dictionary := range []struct {
+			field *array.BinaryDictionaryBuilder
+			value string
+		}{
+			{hostTypeField, location.HostType},
+			{versionField, location.Version},
+			{licenseField, location.License},
+			{evidenceField, location.Evidence},
+		} {
+			err := appendDictionary(dictionary.field, dictionary.value)
+			if err != nil {
+				return err
+			}
+		}
+
+		appendString(repositoryInstitutionField, location.RepositoryInstitution)
+		appendString(pmhIdField, location.PmhId)
+		appendString(endpointIdField, location.EndpointId)
+
+		// Unpaywall dates are sometimes timestamps, and sometimes missing.
+		oaDate, err := time.Parse("2006-01-02", location.OaDate[:min(len(location.OaDate), 10)])
 		if err != nil {
-			fmt.Println(err)
+			oaDateField.AppendNull()
+		} else {
+			oaDateField.Append(arrow.Date32FromTime(oaDate))
 		}
-	}()
+	}
 
-	record := recordBuilder.NewRecord()
-	defer record.Release()
+	return nil
+}
 
-	return writer.Write(record)
+// isBestOaLocation returns whether location is the best open access location.
+// Older Unpaywall metadata doesn't mark the best location, so locations are
+// also best if they have the URL of best_oa_location.
+func isBestOaLocation(location OpenAccessLocation, best *OpenAccessLocation) bool {
+	return location.IsBest || (best != nil && best.URL == location.URL)
+}
+
+func writeRecords(schema *arrow.Schema, recordBuilder *array.RecordBuilder, outDir, outTable string) error {
+	return subsets.WriteRecord(filepath.Join(outDir, outTable+tables.ParquetExt), schema, recordBuilder)
 }
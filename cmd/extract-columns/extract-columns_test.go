@@ -0,0 +1,271 @@
+package main
+
+import (
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"testing"
+)
+
+func TestLicenseSpdx(t *testing.T) {
+	licenses := papers.NewLicenseMapping()
+	unknownLicenses := make(map[string]int)
+
+	var got []string
+	for _, raw := range []string{"cc-by", "CC-BY-4.0", "Open Government Licence - Canada", "CC-BY-4.0", "other"} {
+		got = append(got, licenseSpdx(licenses, raw, unknownLicenses))
+	}
+
+	want := []string{"LicenseRef-cc-by", papers.UnknownLicense, "LicenseRef-ogl-canada", papers.UnknownLicense, papers.UnknownLicense}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	wantUnknown := map[string]int{"CC-BY-4.0": 2, "other": 1}
+	if diff := cmp.Diff(wantUnknown, unknownLicenses); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// rows returns the values of the records built so far, with nulls as "<null>".
+func rows(recordBuilder *array.RecordBuilder) [][]string {
+	record := recordBuilder.NewRecord()
+	defer record.Release()
+
+	var result [][]string
+	for i := range int(record.NumRows()) {
+		var row []string
+		for _, column := range record.Columns() {
+			if column.IsNull(i) {
+				row = append(row, "<null>")
+			} else {
+				row = append(row, column.ValueStr(i))
+			}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+func TestAuthorIndex_Add(t *testing.T) {
+	tcs := []struct {
+		name    string
+		authors []Author
+		want    []uint32
+	}{
+		{
+			// Names are compared by their normalized keys.
+			name: "same name",
+			authors: []Author{
+				{Given: "Ada", Family: "Lovelace"},
+				{Given: "ada", Family: "LOVELACE"},
+			},
+			want: []uint32{1, 1},
+		},
+		{
+			name: "different names",
+			authors: []Author{
+				{Given: "Ada", Family: "Lovelace"},
+				{Given: "Alan", Family: "Turing"},
+			},
+			want: []uint32{1, 2},
+		},
+		{
+			// Authors who share a name at different institutions differ.
+			name: "first affiliation",
+			authors: []Author{
+				{Given: "Ada", Family: "Lovelace", Affiliation: Affiliations{"University of London"}},
+				{Given: "Ada", Family: "Lovelace", Affiliation: Affiliations{"university of london", "Royal Society"}},
+				{Given: "Ada", Family: "Lovelace", Affiliation: Affiliations{"Royal Society", "University of London"}},
+				{Given: "Ada", Family: "Lovelace"},
+			},
+			want: []uint32{1, 1, 2, 3},
+		},
+		{
+			// Either part of the name is enough.
+			name: "partial name",
+			authors: []Author{
+				{Family: "Lovelace"},
+				{Given: "Ada"},
+				{Family: "Lovelace"},
+			},
+			want: []uint32{1, 2, 1},
+		},
+		{
+			name: "no name",
+			authors: []Author{
+				{Affiliation: Affiliations{"University of London"}},
+				{Given: " ", Family: ""},
+			},
+			want: []uint32{0, 0},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			authors := newAuthorIndex()
+			var got []uint32
+			for _, author := range tc.authors {
+				authorId, found := authors.add(1, author)
+				if found != (authorId != 0) {
+					t.Errorf("got id %d and found %t for %+v", authorId, found, author)
+				}
+				got = append(got, authorId)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAppendPaperAuthors(t *testing.T) {
+	lovelace := Author{Given: "Ada", Family: "Lovelace", Sequence: "first", Affiliation: Affiliations{"University of London"}}
+	turing := Author{Given: "Alan", Family: "Turing", Sequence: "additional"}
+	hopper := Author{Given: "Grace", Family: "Hopper"}
+
+	paperAuthorsRecordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.PaperAuthors)
+	defer paperAuthorsRecordBuilder.Release()
+	authorsRecordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.Authors)
+	defer authorsRecordBuilder.Release()
+
+	authors := newAuthorIndex()
+	for i, paper := range []*Paper{
+		// Authors keep their listed position, even after an author without a
+		// name. Lovelace is listed twice but counts one paper.
+		{ZAuthors: Authors{turing, {Sequence: "additional"}, lovelace, lovelace}},
+		// Glutton authors are only used without z_authors.
+		{ZAuthors: Authors{hopper}, Glutton: Glutton{Author: Authors{turing}}},
+		{Glutton: Glutton{Author: Authors{lovelace}}},
+		{},
+	} {
+		// Paper ids start at 1.
+		err := appendPaperAuthors(paperAuthorsRecordBuilder, authors, uint32(i+1), paper)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// paper_id, author_id, position, sequence, affiliations, source
+	wantPaperAuthors := [][]string{
+		{"1", "1", "0", "additional", "[]", "z_authors"},
+		{"1", "2", "2", "first", `["University of London"]`, "z_authors"},
+		{"1", "2", "3", "first", `["University of London"]`, "z_authors"},
+		{"2", "3", "0", "<null>", "[]", "z_authors"},
+		{"3", "2", "0", "first", `["University of London"]`, "glutton"},
+	}
+	if diff := cmp.Diff(wantPaperAuthors, rows(paperAuthorsRecordBuilder)); diff != "" {
+		t.Errorf("paper authors: %s", diff)
+	}
+
+	appendAuthors(authorsRecordBuilder, authors)
+
+	// author_id, given, family, papers
+	wantAuthors := [][]string{
+		{"1", "Alan", "Turing", "1"},
+		{"2", "Ada", "Lovelace", "2"},
+		{"3", "Grace", "Hopper", "1"},
+	}
+	if diff := cmp.Diff(wantAuthors, rows(authorsRecordBuilder)); diff != "" {
+		t.Errorf("authors: %s", diff)
+	}
+}
+
+func TestIsBestOaLocation(t *testing.T) {
+	tcs := []struct {
+		name     string
+		location OpenAccessLocation
+		best     *OpenAccessLocation
+		want     bool
+	}{
+		{name: "marked", location: OpenAccessLocation{URL: "https://a", IsBest: true}, want: true},
+		{name: "marked with other best", location: OpenAccessLocation{URL: "https://a", IsBest: true},
+			best: &OpenAccessLocation{URL: "https://b"}, want: true},
+		{name: "best url", location: OpenAccessLocation{URL: "https://a"}, best: &OpenAccessLocation{URL: "https://a"}, want: true},
+		{name: "other best url", location: OpenAccessLocation{URL: "https://a"}, best: &OpenAccessLocation{URL: "https://b"}},
+		{name: "no best", location: OpenAccessLocation{URL: "https://a"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isBestOaLocation(tc.location, tc.best); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppendOaLocations(t *testing.T) {
+	tcs := []struct {
+		name  string
+		paper *Paper
+		// paper_id, location_index, is_best, url, url_for_pdf, host_type, oa_date
+		want [][]string
+	}{
+		{
+			// Locations without a URL are skipped, but keep their place in the
+			// indices of the others.
+			name: "empty url",
+			paper: &Paper{OaLocations: []OpenAccessLocation{
+				{URL: "https://a", URLForPdf: "https://a/pdf", HostType: "publisher", OaDate: "2020-01-02"},
+				{URLForPdf: "https://b/pdf", HostType: "repository", IsBest: true},
+				{URL: "https://c", HostType: "repository", OaDate: "2021-03-04T05:06:07"},
+			}},
+			want: [][]string{
+				{"1", "0", "false", "https://a", "https://a/pdf", "publisher", "2020-01-02"},
+				{"1", "2", "false", "https://c", "<null>", "repository", "2021-03-04"},
+			},
+		},
+		{
+			name: "marked best",
+			paper: &Paper{OaLocations: []OpenAccessLocation{
+				{URL: "https://a"},
+				{URL: "https://b", IsBest: true},
+			}},
+			want: [][]string{
+				{"1", "0", "false", "https://a", "<null>", "<null>", "<null>"},
+				{"1", "1", "true", "https://b", "<null>", "<null>", "<null>"},
+			},
+		},
+		{
+			name: "best url",
+			paper: &Paper{
+				OaLocations: []OpenAccessLocation{
+					{URL: "https://a"},
+					{URL: "https://b"},
+				},
+				BestOaLocation: &OpenAccessLocation{URL: "https://b"},
+			},
+			want: [][]string{
+				{"1", "0", "false", "https://a", "<null>", "<null>", "<null>"},
+				{"1", "1", "true", "https://b", "<null>", "<null>", "<null>"},
+			},
+		},
+		{
+			name:  "no locations",
+			paper: &Paper{BestOaLocation: &OpenAccessLocation{URL: "https://a"}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.OaLocations)
+			defer recordBuilder.Release()
+
+			err := appendOaLocations(recordBuilder, 1, tc.paper)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got [][]string
+			for _, row := range rows(recordBuilder) {
+				got = append(got, []string{row[0], row[1], row[2], row[3], row[4], row[6], row[13]})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
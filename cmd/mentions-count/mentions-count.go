@@ -1,23 +1,56 @@
 package main
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
 	"github.com/apache/arrow/go/v18/arrow/array"
-	"github.com/apache/arrow/go/v18/parquet/file"
-	"github.com/apache/arrow/go/v18/parquet/pqarrow"
 	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/associations"
+	"github.com/willbeason/software-mentions/pkg/graphs"
+	"github.com/willbeason/software-mentions/pkg/subsets"
 	"github.com/willbeason/software-mentions/pkg/tables"
-	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"slices"
 	"sort"
+	"strconv"
+)
+
+const (
+	FlagSource         = "source"
+	FlagSoftwareColumn = "software-column"
+	FlagMinPapers      = "min-papers"
+	FlagMinComentions  = "min-comentions"
+	FlagIgnore         = "ignore"
+	FlagPurpose        = "purpose"
+	FlagScope          = "scope"
+	FlagMinCertainty   = "min-certainty"
+	FlagTop            = "top"
+	FlagRankBy         = "rank-by"
+	FlagStratifyBy     = "stratify-by"
+	FlagStats          = "stats"
+	FlagCpuProfile     = "cpuprofile"
 )
 
 func main() {
-	cpuprofile = cmd.Flags().String("cpuprofile", "", "write cpu profile to `file`")
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions and purpose_assessments tables")
+	cmd.Flags().String(FlagSoftwareColumn, tables.SoftwareNormalizedFieldName, "mentions column identifying the software")
+	cmd.Flags().Int(FlagMinPapers, 100, "minimum number of papers mentioning a software for it to be a node")
+	cmd.Flags().Int(FlagMinComentions, 100, "minimum number of papers mentioning both software for them to share an edge")
+	cmd.Flags().StringSlice(FlagIgnore, defaultIgnore, "software to leave out of the network")
+	cmd.Flags().StringSlice(FlagPurpose, nil, "only count mentions assessed with one of these purposes, of [used|created|shared]")
+	cmd.Flags().String(FlagScope, "document", "scope of the purpose assessments, one of [document|local]")
+	cmd.Flags().Float64(FlagMinCertainty, 0.5, "certainty_score a purpose assessment must be above")
+	cmd.Flags().Int(FlagTop, 20, "number of the largest nodes and edges to print")
+	cmd.Flags().String(FlagRankBy, string(associations.Count), "measure to order edges by, one of [count|pmi|npmi|jaccard|lift|chi_square|p_value|log_p_value]")
+	cmd.Flags().String(FlagStratifyBy, "", "papers column to compute association statistics within, one of [year|venue]")
+	cmd.Flags().String(FlagStats, "", "write association statistics of each edge to this CSV `file`")
+	cmd.Flags().String(FlagCpuProfile, "", "write cpu profile to `file`")
 
 	err := cmd.Execute()
 	if err != nil {
@@ -26,20 +59,57 @@ func main() {
 }
 
 var cmd = cobra.Command{
-	Use:     "mentions-count DIR",
-	Short:   "Count the licenses in a directory of .software.jsonl.gz files",
-	Args:    cobra.ExactArgs(1),
+	Use:   "mentions-count IN_DIR OUT_FILE",
+	Short: "Export the network of software mentioned in the same papers",
+	Long: `Export the network of software mentioned in the same papers.
+
+Each node is a software mentioned in at least --min-papers papers, with the
+number of papers and mentions and the first year a paper mentioned it. Each edge
+joins two software mentioned together in at least --min-comentions papers, and
+is weighted by the number of those papers. With --purpose, only mentions with a
+purpose assessment of one of the purposes at --scope with a certainty score
+above --min-certainty count.
+
+Edges also have association measures of the two software over all papers: PMI
+and NPMI, Jaccard similarity, lift, and the chi-square statistic with its
+p-value. P-values underflow to 0 for chi-square statistics above about 1500,
+common in the full dataset, so edges also have log_p_value, the natural
+logarithm of the p-value, which does not. --rank-by orders edges by any of
+these, with p_value and log_p_value ascending by log_p_value and the others
+descending. --stats writes the measures to a CSV file, and with
+--stratify-by also within the papers of each published year or venue; papers
+without one are left out of the strata.
+
+The format is chosen by the extension of OUT_FILE: .csv for an edge list, with
+the nodes in a .nodes.csv file beside it, .graphml, or .gexf.`,
+	Args:    cobra.ExactArgs(2),
 	Version: "0.1.0",
 	RunE:    runE,
 }
 
-var cpuprofile *string
+var ErrCountMentions = errors.New("counting software mentions")
+
+var defaultIgnore = []string{
+	"script",
+	"code",
+	"scripts",
+	"survival",
+	"library",
+	"software",
+	"interface",
+	"program",
+}
 
-var ErrCountLicenses = errors.New("counting software mentions")
+// unknownYear marks papers without a published_year.
+const unknownYear = 0
 
 func runE(cmd *cobra.Command, args []string) error {
-	if *cpuprofile != "" {
-		f, err := os.Create(*cpuprofile)
+	cpuprofile, err := cmd.Flags().GetString(FlagCpuProfile)
+	if err != nil {
+		return err
+	}
+	if cpuprofile != "" {
+		f, err := os.Create(cpuprofile)
 		if err != nil {
 			log.Fatal("could not create CPU profile: ", err)
 		}
@@ -55,147 +125,552 @@ func runE(cmd *cobra.Command, args []string) error {
 		defer pprof.StopCPUProfile()
 	}
 
-	inDir := args[0]
+	ctx := cmd.Context()
 
-	//softwarePath := filepath.Join(inDir, tables.Software+tables.ParquetExt)
-	mentionsPath := filepath.Join(inDir, tables.Software+tables.ParquetExt)
+	inDir := args[0]
+	outPath := args[1]
+	err = graphs.CheckPath(outPath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
 
-	mentionsReader, err := file.OpenParquetFile(mentionsPath, true)
+	source, err := cmd.Flags().GetString(FlagSource)
 	if err != nil {
 		return err
 	}
-
-	fileReader, err := pqarrow.NewFileReader(
-		mentionsReader,
-		pqarrow.ArrowReadProperties{
-			Parallel:  true,
-			BatchSize: 1 << 20,
-		},
-		nil)
+	softwareColumn, err := cmd.Flags().GetString(FlagSoftwareColumn)
 	if err != nil {
 		return err
 	}
-
-	ctx := cmd.Context()
-	recordReader, err := fileReader.GetRecordReader(ctx, nil, nil)
+	minPapers, err := cmd.Flags().GetInt(FlagMinPapers)
+	if err != nil {
+		return err
+	}
+	minComentions, err := cmd.Flags().GetInt(FlagMinComentions)
+	if err != nil {
+		return err
+	}
+	ignoreList, err := cmd.Flags().GetStringSlice(FlagIgnore)
+	if err != nil {
+		return err
+	}
+	top, err := cmd.Flags().GetInt(FlagTop)
+	if err != nil {
+		return err
+	}
+	byPurpose, err := purposeFilterFlags(cmd)
+	if err != nil {
+		return err
+	}
+	rankByString, err := cmd.Flags().GetString(FlagRankBy)
+	if err != nil {
+		return err
+	}
+	rankBy, err := associations.ParseMeasure(rankByString)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
+	stratifyBy, err := cmd.Flags().GetString(FlagStratifyBy)
+	if err != nil {
+		return err
+	}
+	var stratumColumn string
+	switch stratifyBy {
+	case "":
+	case "year":
+		stratumColumn = tables.PublishedYearFieldName
+	case "venue":
+		stratumColumn = tables.PublicationVenueFieldName
+	default:
+		return fmt.Errorf("%w: unknown stratification %q", ErrCountMentions, stratifyBy)
+	}
+	statsPath, err := cmd.Flags().GetString(FlagStats)
 	if err != nil {
 		return err
 	}
 
-	softwareByPaper := make(map[string]map[string]bool)
+	ignore := make(map[string]bool, len(ignoreList))
+	for _, software := range ignoreList {
+		ignore[software] = true
+	}
 
-	for {
-		record, err := recordReader.Read()
+	purposes, err := subsets.OpenPurposes(inDir, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
+	mentionsPath := purposes.MentionsPath
+	papersPath := filepath.Join(inDir, tables.PapersName+tables.ParquetExt)
+
+	var assessedMentions map[string]struct{}
+	if byPurpose != nil {
+		assessedMentions, err = purposes.MatchingMentions(ctx, byPurpose.scope, byPurpose.purposes, byPurpose.minCertainty)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
+			return fmt.Errorf("%w: filtering purpose assessments: %w", ErrCountMentions, err)
+		}
+	}
+
+	// Software are numbered in order of first mention.
+	var softwareNames []string
+	softwareIds := make(map[string]uint32)
+	var nMentions []int
+	softwareByPaper := make(map[uint32][]uint32)
+
+	columns := []string{tables.SoftwareMentionIdFieldName, tables.PaperIdFieldName, softwareColumn}
+	err = subsets.Scan(ctx, mentionsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := subsets.Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		softwareColumn, err := subsets.Column[*array.String](record, softwareColumn)
+		if err != nil {
+			return err
+		}
+
+		for i := range int(record.NumRows()) {
+			if softwareColumn.IsNull(i) {
+				continue
 			}
+			software := softwareColumn.Value(i)
+			if software == "" || ignore[software] {
+				continue
+			}
+			if assessedMentions != nil {
+				if _, found := assessedMentions[mentionIdColumn.Value(i)]; !found {
+					continue
+				}
+			}
+
+			softwareId, found := softwareIds[software]
+			if !found {
+				softwareId = uint32(len(softwareNames))
+				softwareIds[software] = softwareId
+				softwareNames = append(softwareNames, software)
+				nMentions = append(nMentions, 0)
+			}
+			nMentions[softwareId]++
+
+			paperId := paperIdColumn.Value(i)
+			softwareByPaper[paperId] = append(softwareByPaper[paperId], softwareId)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading mentions: %w", ErrCountMentions, err)
+	}
+
+	years := make(map[uint32]uint16, len(softwareByPaper))
+	// The stratum of each paper with mentions, and the number of papers in
+	// each stratum.
+	paperStrata := make(map[uint32]string)
+	stratumPapers := make(map[string]int)
+	nTotalPapers := 0
+	columns = []string{tables.PaperIdFieldName, tables.PublishedYearFieldName}
+	if stratumColumn != "" {
+		columns = append(columns, stratumColumn)
+	}
+	err = subsets.Scan(ctx, papersPath, columns, func(record arrow.Record) error {
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		yearColumn, err := subsets.Column[*array.Uint16](record, tables.PublishedYearFieldName)
+		if err != nil {
 			return err
 		}
 
-		paperIds := record.Column(0).(*array.String)
-		softwareIds := record.Column(1).(*array.String)
+		var stratum arrow.Array
+		if stratumColumn != "" {
+			stratum = record.Column(record.Schema().FieldIndices(stratumColumn)[0])
+		}
+
+		for i, paperId := range paperIdColumn.Uint32Values() {
+			nTotalPapers++
+			_, hasSoftware := softwareByPaper[paperId]
 
-		for row := range int(record.NumRows()) {
-			paperId := paperIds.Value(row)
-			softwareId := softwareIds.Value(row)
+			if stratum != nil && !stratum.IsNull(i) && stratum.ValueStr(i) != "" {
+				stratumPapers[stratum.ValueStr(i)]++
+				if hasSoftware {
+					paperStrata[paperId] = stratum.ValueStr(i)
+				}
+			}
 
-			if ignoreSoftware[softwareId] {
+			if !hasSoftware || yearColumn.IsNull(i) {
 				continue
 			}
+			years[paperId] = yearColumn.Value(i)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading papers: %w", ErrCountMentions, err)
+	}
 
-			paperSoftware, ok := softwareByPaper[paperId]
-			if !ok {
-				paperSoftware = make(map[string]bool)
+	nPapers := make([]int, len(softwareNames))
+	firstYears := make([]uint16, len(softwareNames))
+	for paperId, paperSoftware := range softwareByPaper {
+		slices.Sort(paperSoftware)
+		paperSoftware = slices.Compact(paperSoftware)
+		softwareByPaper[paperId] = paperSoftware
+
+		year := years[paperId]
+		for _, softwareId := range paperSoftware {
+			nPapers[softwareId]++
+			if year != unknownYear && (firstYears[softwareId] == unknownYear || year < firstYears[softwareId]) {
+				firstYears[softwareId] = year
 			}
+		}
+	}
 
-			paperSoftware[softwareId] = true
-			softwareByPaper[paperId] = paperSoftware
+	var nodes []uint32
+	allowed := make([]bool, len(softwareNames))
+	for softwareId, count := range nPapers {
+		if count >= minPapers {
+			nodes = append(nodes, uint32(softwareId))
+			allowed[softwareId] = true
 		}
 	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nPapers[nodes[i]] != nPapers[nodes[j]] {
+			return nPapers[nodes[i]] > nPapers[nodes[j]]
+		}
+		return softwareNames[nodes[i]] < softwareNames[nodes[j]]
+	})
 
-	nMentions := make(map[string]int)
+	comentionCounts := make(map[MentionDyad]int)
 	for _, paperSoftware := range softwareByPaper {
-		for softwareId := range paperSoftware {
-			nMentions[softwareId]++
+		for i, k := range paperSoftware {
+			if !allowed[k] {
+				continue
+			}
+			for _, l := range paperSoftware[i+1:] {
+				if allowed[l] {
+					comentionCounts[MentionDyad{k, l}]++
+				}
+			}
 		}
 	}
 
-	allowMap := make(map[string]bool)
-
-	var softwareList []string
-	for k, count := range nMentions {
-		if count < 100 {
+	var edges []dyadStats
+	for dyad, count := range comentionCounts {
+		if count < minComentions {
 			continue
 		}
-		allowMap[k] = true
-		softwareList = append(softwareList, k)
+
+		counts := associations.Counts{Total: nTotalPapers, A: nPapers[dyad[0]], B: nPapers[dyad[1]], Both: count}
+		edges = append(edges, dyadStats{dyad: dyad, counts: counts, measures: associations.Compute(counts)})
+	}
+	sortDyads(edges, rankBy, softwareNames)
+
+	fmt.Printf("nodes: %d\nedges: %d\n", len(nodes), len(edges))
+	for i, softwareId := range nodes[:min(top, len(nodes))] {
+		fmt.Printf("%d;%s;%d\n", i, softwareNames[softwareId], nPapers[softwareId])
+	}
+	for _, edge := range edges[:min(top, len(edges))] {
+		fmt.Printf("%s;%s;%d;%s=%s\n", softwareNames[edge.dyad[0]], softwareNames[edge.dyad[1]], edge.counts.Both,
+			rankBy, formatFloat(edge.value(rankBy)))
+	}
+	underflows := 0
+	for _, edge := range edges {
+		if edge.measures.PValue == 0 {
+			underflows++
+		}
+	}
+	if underflows > 0 {
+		fmt.Printf("p_value underflows to 0 for %d edges; compare them by log_p_value\n", underflows)
+	}
+
+	graph := &graphs.Graph{
+		NodeAttributes: []graphs.Attribute{
+			{Name: "papers", Type: graphs.Int},
+			{Name: "mentions", Type: graphs.Int},
+			{Name: "first_year", Type: graphs.Int},
+		},
+		EdgeAttributes: measureAttributes,
+	}
+	for _, softwareId := range nodes {
+		firstYear := ""
+		if firstYears[softwareId] != unknownYear {
+			firstYear = strconv.Itoa(int(firstYears[softwareId]))
+		}
+
+		graph.Nodes = append(graph.Nodes, graphs.Node{
+			Id:    softwareNames[softwareId],
+			Label: softwareNames[softwareId],
+			Values: []string{
+				strconv.Itoa(nPapers[softwareId]),
+				strconv.Itoa(nMentions[softwareId]),
+				firstYear,
+			},
+		})
+	}
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, graphs.Edge{
+			Source: softwareNames[edge.dyad[0]],
+			Target: softwareNames[edge.dyad[1]],
+			Weight: float64(edge.counts.Both),
+			Values: edge.measureValues(),
+		})
+	}
+
+	err = graphs.WriteFile(outPath, graph)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
+
+	if statsPath == "" {
+		return nil
 	}
 
-	fmt.Println(len(softwareList))
+	var strata []string
+	stratumEdges := make(map[string][]dyadStats)
+	if stratumColumn != "" {
+		strata, stratumEdges = stratify(edges, softwareByPaper, paperStrata, stratumPapers)
+		for _, stratum := range strata {
+			sortDyads(stratumEdges[stratum], rankBy, softwareNames)
+		}
+	}
 
-	sort.Slice(softwareList, func(i, j int) bool {
-		return nMentions[softwareList[i]] > nMentions[softwareList[j]]
+	err = writeStats(statsPath, softwareNames, edges, strata, stratumEdges)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
+
+	return nil
+}
+
+// MentionDyad is a pair of software ids, the lesser first.
+type MentionDyad [2]uint32
+
+// dyadStats is the association of the software of a dyad among some papers.
+type dyadStats struct {
+	dyad     MentionDyad
+	counts   associations.Counts
+	measures associations.Measures
+}
+
+func (s dyadStats) value(measure associations.Measure) float64 {
+	switch measure {
+	case associations.Count:
+		return float64(s.counts.Both)
+	case associations.PValue:
+		return s.measures.PValue
+	case associations.LogPValue:
+		return s.measures.LogPValue
+	default:
+		return measure.Score(s.counts, s.measures)
+	}
+}
+
+var measureAttributes = []graphs.Attribute{
+	{Name: string(associations.PMI), Type: graphs.Double},
+	{Name: string(associations.NPMI), Type: graphs.Double},
+	{Name: string(associations.Jaccard), Type: graphs.Double},
+	{Name: string(associations.Lift), Type: graphs.Double},
+	{Name: string(associations.ChiSquare), Type: graphs.Double},
+	{Name: string(associations.PValue), Type: graphs.Double},
+	{Name: string(associations.LogPValue), Type: graphs.Double},
+}
+
+// measureValues returns the values of the measureAttributes of s.
+func (s dyadStats) measureValues() []string {
+	m := s.measures
+	return []string{
+		formatFloat(m.PMI),
+		formatFloat(m.NPMI),
+		formatFloat(m.Jaccard),
+		formatFloat(m.Lift),
+		formatFloat(m.ChiSquare),
+		formatFloat(m.PValue),
+		formatFloat(m.LogPValue),
+	}
+}
+
+// formatFloat formats x, or returns "" if x is undefined.
+func formatFloat(x float64) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(x, 'g', 6, 64)
+}
+
+// sortDyads orders dyads by decreasing score of measure, then by decreasing
+// count, then by name.
+func sortDyads(dyads []dyadStats, measure associations.Measure, softwareNames []string) {
+	sort.Slice(dyads, func(i, j int) bool {
+		left, right := dyads[i], dyads[j]
+		leftScore, rightScore := measure.Score(left.counts, left.measures), measure.Score(right.counts, right.measures)
+		if leftScore != rightScore {
+			return leftScore > rightScore
+		}
+		if left.counts.Both != right.counts.Both {
+			return left.counts.Both > right.counts.Both
+		}
+		if left.dyad[0] != right.dyad[0] {
+			return softwareNames[left.dyad[0]] < softwareNames[right.dyad[0]]
+		}
+		return softwareNames[left.dyad[1]] < softwareNames[right.dyad[1]]
 	})
+}
 
-	for i, softwareId := range softwareList[:10] {
-		fmt.Printf("%d;%s;%d\n", i, softwareId, nMentions[softwareId])
+// stratify computes the association of the software of each edge within each
+// stratum of papers. Dyads never mentioned together in a stratum are left out
+// of it.
+func stratify(
+	edges []dyadStats,
+	softwareByPaper map[uint32][]uint32,
+	paperStrata map[uint32]string,
+	stratumPapers map[string]int,
+) ([]string, map[string][]dyadStats) {
+	isEdge := make(map[MentionDyad]bool, len(edges))
+	isNode := make(map[uint32]bool)
+	for _, edge := range edges {
+		isEdge[edge.dyad] = true
+		isNode[edge.dyad[0]] = true
+		isNode[edge.dyad[1]] = true
 	}
 
-	comentionsCounts := make(map[MentionDyad]int)
+	nPapers := make(map[string]map[uint32]int)
+	comentionCounts := make(map[string]map[MentionDyad]int)
+	for paperId, paperSoftware := range softwareByPaper {
+		stratum, found := paperStrata[paperId]
+		if !found {
+			continue
+		}
+		if _, found = nPapers[stratum]; !found {
+			nPapers[stratum] = make(map[uint32]int)
+			comentionCounts[stratum] = make(map[MentionDyad]int)
+		}
 
-	for _, mentions := range softwareByPaper {
-		for k := range mentions {
-			if !allowMap[k] {
+		for i, k := range paperSoftware {
+			if !isNode[k] {
 				continue
 			}
-			for l := range mentions {
-				if !allowMap[l] {
-					continue
-				}
-
-				if k >= l {
-					continue
+			nPapers[stratum][k]++
+			for _, l := range paperSoftware[i+1:] {
+				if isEdge[MentionDyad{k, l}] {
+					comentionCounts[stratum][MentionDyad{k, l}]++
 				}
-
-				comentionsCounts[MentionDyad{_1: k, _2: l}]++
 			}
 		}
 	}
 
-	var comentionsList []MentionDyad
-	for k, count := range comentionsCounts {
-		if count < 100 {
+	strata := make([]string, 0, len(comentionCounts))
+	result := make(map[string][]dyadStats, len(comentionCounts))
+	for stratum, counts := range comentionCounts {
+		if len(counts) == 0 {
 			continue
 		}
-		comentionsList = append(comentionsList, k)
+		strata = append(strata, stratum)
+
+		for dyad, count := range counts {
+			c := associations.Counts{
+				Total: stratumPapers[stratum],
+				A:     nPapers[stratum][dyad[0]],
+				B:     nPapers[stratum][dyad[1]],
+				Both:  count,
+			}
+			result[stratum] = append(result[stratum], dyadStats{dyad: dyad, counts: c, measures: associations.Compute(c)})
+		}
 	}
+	sort.Strings(strata)
 
-	sort.Slice(comentionsList, func(i, j int) bool {
-		return comentionsCounts[comentionsList[i]] > comentionsCounts[comentionsList[j]]
-	})
+	return strata, result
+}
 
-	for _, dyad := range comentionsList[:20] {
-		fmt.Printf("%s;%s;%d\n", dyad._1, dyad._2, comentionsCounts[dyad])
+// writeStats writes the association statistics of each edge over all papers,
+// with an empty stratum, followed by those within each stratum.
+func writeStats(path string, softwareNames []string, edges []dyadStats, strata []string, stratumEdges map[string][]dyadStats) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", path, err)
 	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
 
-	return nil
-}
+	writer := csv.NewWriter(file)
+	header := []string{"stratum", "source", "target", "papers", "source_papers", "target_papers", "comentions"}
+	for _, attribute := range measureAttributes {
+		header = append(header, attribute.Name)
+	}
+	err = writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	writeRows := func(stratum string, dyads []dyadStats) error {
+		for _, s := range dyads {
+			row := append([]string{
+				stratum,
+				softwareNames[s.dyad[0]],
+				softwareNames[s.dyad[1]],
+				strconv.Itoa(s.counts.Total),
+				strconv.Itoa(s.counts.A),
+				strconv.Itoa(s.counts.B),
+				strconv.Itoa(s.counts.Both),
+			}, s.measureValues()...)
+			err := writer.Write(row)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = writeRows("", edges)
+	if err != nil {
+		return err
+	}
+	for _, stratum := range strata {
+		err = writeRows(stratum, stratumEdges[stratum])
+		if err != nil {
+			return err
+		}
+	}
 
-type MentionDyad struct {
-	_1 string
-	_2 string
+	writer.Flush()
+	return writer.Error()
 }
 
-var ignoreSoftware = map[string]bool{
-	"script":    true,
-	"code":      true,
-	"scripts":   true,
-	"survival":  true,
-	"library":   true,
-	"software":  true,
-	"interface": true,
-	"program":   true,
+// purposeFilter selects the mentions with a purpose assessment at scope of one
+// of purposes with a certainty_score above minCertainty.
+type purposeFilter struct {
+	scope        string
+	purposes     []string
+	minCertainty float64
 }
 
-const IncrEvery = 1 << 10
+// purposeFilterFlags returns the purpose assessments the flags select, or nil
+// if mentions are not filtered by purpose.
+func purposeFilterFlags(cmd *cobra.Command) (*purposeFilter, error) {
+	purposes, err := cmd.Flags().GetStringSlice(FlagPurpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(purposes) == 0 {
+		return nil, nil
+	}
+	scope, err := cmd.Flags().GetString(FlagScope)
+	if err != nil {
+		return nil, err
+	}
+	minCertainty, err := cmd.Flags().GetFloat64(FlagMinCertainty)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the flags before reading any tables.
+	_, err = subsets.PurposePredicate(scope, purposes, minCertainty)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCountMentions, err)
+	}
+
+	return &purposeFilter{scope: scope, purposes: purposes, minCertainty: minCertainty}, nil
+}
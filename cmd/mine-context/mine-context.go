@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/contexts"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"os"
+	"path/filepath"
+)
+
+const (
+	FlagSource = "source"
+)
+
+func init() {
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions table")
+}
+
+func main() {
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "mine-context IN_DIR OUT_DIR",
+	Short: "Extract identifiers from the context text of mentions",
+	Long: `Extract identifiers from the context text of mentions.
+
+Mines the context_full_text of each mention for RRIDs, DOIs, Zenodo records,
+Software Heritage ids, GitHub repositories, and versions, and writes them with
+their character spans to the context_identifiers table in OUT_DIR, keyed by
+software_mention_id.`,
+	Args:    cobra.ExactArgs(2),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrMineContext = errors.New("mining mention contexts")
+
+func runE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inDir := args[0]
+	outDir := args[1]
+
+	source, err := cmd.Flags().GetString(FlagSource)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(outDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("%w: creating output directory: %w", ErrMineContext, err)
+	}
+
+	mentionsPath := filepath.Join(inDir, tables.MentionsName+"."+source+tables.ParquetExt)
+	outPath := filepath.Join(outDir, tables.ContextIdentifiersName+"."+source+tables.ParquetExt)
+
+	writer, err := subsets.NewWriter(tables.ContextIdentifiers, outPath)
+	if err != nil {
+		return fmt.Errorf("%w: creating %q: %w", ErrMineContext, outPath, err)
+	}
+
+	defer func() {
+		err := writer.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.ContextIdentifiers)
+	defer recordBuilder.Release()
+
+	fields := recordBuilder.Fields()
+	mentionIdField := fields[0].(*array.StringBuilder)
+	paperIdField := fields[1].(*array.Uint32Builder)
+	kindField := fields[2].(*array.BinaryDictionaryBuilder)
+	valueField := fields[3].(*array.StringBuilder)
+	rawField := fields[4].(*array.StringBuilder)
+	startField := fields[5].(*array.Uint32Builder)
+	endField := fields[6].(*array.Uint32Builder)
+
+	counts := make(map[contexts.Kind]int)
+
+	columns := []string{tables.SoftwareMentionIdFieldName, tables.PaperIdFieldName, tables.ContextFieldName}
+	err = subsets.Scan(ctx, mentionsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := subsets.Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		contextColumn, err := subsets.Column[*array.String](record, tables.ContextFieldName)
+		if err != nil {
+			return err
+		}
+
+		for i := range contextColumn.Len() {
+			if contextColumn.IsNull(i) {
+				continue
+			}
+
+			for _, id := range contexts.Mine(contextColumn.Value(i)) {
+				mentionIdField.Append(mentionIdColumn.Value(i))
+				paperIdField.Append(paperIdColumn.Value(i))
+				err = kindField.AppendString(string(id.Kind))
+				if err != nil {
+					return fmt.Errorf("appending kind: %w", err)
+				}
+				valueField.Append(id.Value)
+				rawField.Append(id.Raw)
+				startField.Append(uint32(id.Start))
+				endField.Append(uint32(id.End))
+
+				counts[id.Kind]++
+			}
+		}
+
+		out := recordBuilder.NewRecord()
+		defer out.Release()
+		return writer.Write(out)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMineContext, err)
+	}
+
+	for _, kind := range contexts.Kinds {
+		fmt.Printf("%s: %d\n", kind, counts[kind])
+	}
+
+	return nil
+}
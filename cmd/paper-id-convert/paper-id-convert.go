@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -10,18 +11,35 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb"
 	"github.com/vbauerster/mpb/decor"
+	"github.com/willbeason/bondsmith/fileio"
 	"github.com/willbeason/software-mentions/pkg/papers"
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
 	"golang.org/x/term"
 	"google.golang.org/protobuf/proto"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	FlagValidate = "validate"
+	FlagOaLink   = "oa-link"
+	FlagWorkers  = "workers"
+)
+
+// batchSize is the number of lines each worker converts at a time.
+const batchSize = 1 << 10
+
 func main() {
-	cmd.Flags().Bool("validate", false, "validate the transform is not lossy")
-	cmd.Flags().Bool("oa-link", true, "include oa_link in the output")
+	cmd.Flags().Bool(FlagValidate, false, "validate the transform is not lossy")
+	cmd.Flags().Bool(FlagOaLink, true, "include oa_link in the output")
+	cmd.Flags().Int(FlagWorkers, runtime.NumCPU(), "number of workers converting entries")
 	err := cmd.Execute()
 
 	if err != nil {
@@ -30,8 +48,8 @@ func main() {
 }
 
 var cmd = cobra.Command{
-	Use:     "paper-id-convert INFILE OUTFILE",
-	Short:   "Collect statistics about keys and values in .jsonl files",
+	Use:     "paper-id-convert IN OUTFILE",
+	Short:   "Convert paper ids from .jsonl, .jsonl.gz, or a directory of .jsonl.gz files to .pbl",
 	Args:    cobra.ExactArgs(2),
 	Version: "0.1.0",
 	RunE:    runE,
@@ -39,49 +57,97 @@ var cmd = cobra.Command{
 
 var ErrConvert = errors.New("converting to proto")
 
+// paperPattern matches the paper metadata files in a directory, as in
+// extract-columns.
+var paperPattern = regexp.MustCompile(`^([0-9a-f]{2}|gg)\.jsonl\.gz$`)
+
+// failureRecord is the pseudo-field counting entries dropped entirely, either
+// because they are not valid JSON or have an invalid id.
+const failureRecord = "(record)"
+
+type options struct {
+	validate      bool
+	includeOaLink bool
+}
+
+type batch struct {
+	lines     [][]byte
+	bytesRead int64
+	results   chan<- *batchResult
+}
+
+type batchResult struct {
+	// out is the length-prefixed protos of the batch, in order.
+	out []byte
+	// bytesRead is how far into the input files the batch extends.
+	bytesRead int64
+	failures  map[string]int
+	outcomes  ids.Outcomes
+	err       error
+}
+
 func runE(cmd *cobra.Command, args []string) error {
-	validate, err := cmd.Flags().GetBool("validate")
+	validate, err := cmd.Flags().GetBool(FlagValidate)
 	if err != nil {
 		return err
 	}
 
-	includeOaLink, err := cmd.Flags().GetBool("oa-link")
+	includeOaLink, err := cmd.Flags().GetBool(FlagOaLink)
 	if err != nil {
 		return err
 	}
 
-	inPath := args[0]
-	if ext := filepath.Ext(inPath); ext != ".jsonl" {
-		return fmt.Errorf("%w: got input file extension %q but want %q", ErrConvert, ext, ".jsonl")
+	nWorkers, err := cmd.Flags().GetInt(FlagWorkers)
+	if err != nil {
+		return err
+	}
+	if nWorkers < 1 {
+		return fmt.Errorf("%w: need at least one worker, got %d", ErrConvert, nWorkers)
 	}
 
+	opts := options{validate: validate, includeOaLink: includeOaLink}
+
+	inPath := args[0]
 	outPath := args[1]
-	if ext := filepath.Ext(outPath); ext != ".pbl" {
-		return fmt.Errorf("%w: got output file extension %q but want %q", ErrConvert, ext, ".pbl")
+	if ext := filepath.Ext(outPath); ext != papers.PblExt {
+		return fmt.Errorf("%w: got output file extension %q but want %q", ErrConvert, ext, papers.PblExt)
 	}
 
-	file, err := os.Open(inPath)
+	inPaths, err := toInPaths(inPath)
 	if err != nil {
-		return fmt.Errorf("%w: opening %q: %w", ErrConvert, inPath, err)
+		return err
 	}
 
-	reader := bufio.NewReader(file)
-
-	stats, err := file.Stat()
+	totalSize, err := fileio.CalculateSizes(inPaths)
 	if err != nil {
-		return fmt.Errorf("%w: reading stats of %q: %w", ErrConvert, inPath, err)
+		return fmt.Errorf("%w: calculating file sizes: %w", ErrConvert, err)
+	}
+
+	// Progress is measured in bytes of the input files, which may be compressed.
+	countReader := fileio.NewBytesReadReader(fileio.NewMultiReader(inPaths))
+	var reader io.Reader = countReader
+	if strings.HasSuffix(inPaths[0], ".gz") {
+		// gzip correctly handles concatenated files.
+		reader, err = gzip.NewReader(countReader)
+		if err != nil {
+			return fmt.Errorf("%w: creating gzip reader: %w", ErrConvert, err)
+		}
 	}
+	lineReader := bufio.NewReader(reader)
 
 	width, _, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
 		return fmt.Errorf("%w: getting terminal size: %w", ErrConvert, err)
 	}
 	p := mpb.New(mpb.WithWidth(width))
-	bar := p.AddBar(stats.Size(),
+	bar := p.AddBar(totalSize,
 		mpb.PrependDecorators(decor.AverageSpeed(decor.UnitKiB, "%.1f")),
 		mpb.AppendDecorators(decor.AverageETA(decor.ET_STYLE_GO)))
 
 	outFile, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("%w: creating %q: %w", ErrConvert, outPath, err)
+	}
 	defer func() {
 		if err := outFile.Close(); err != nil {
 			fmt.Printf("%v: closing output file %q: %v\n", ErrConvert, outPath, err)
@@ -96,70 +162,233 @@ func runE(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	// Batches are handed to workers in input order, and each batch's result
+	// channel is queued in the same order so the writer preserves it.
+	batches := make(chan batch, nWorkers)
+	ordered := make(chan chan *batchResult, 2*nWorkers)
+	done := make(chan struct{})
+
+	workersWg := sync.WaitGroup{}
+	for range nWorkers {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for b := range batches {
+				result := convertBatch(b.lines, opts)
+				result.bytesRead = b.bytesRead
+				b.results <- result
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(ordered)
+		defer close(batches)
+		readErr <- readBatches(lineReader, countReader, batches, ordered, done)
+	}()
+
+	failures := make(map[string]int)
+	outcomes := &ids.Outcomes{}
+	lastRead := int64(0)
 	start := time.Now()
+	var writeErr error
+	for results := range ordered {
+		result := <-results
+		if writeErr != nil {
+			continue
+		}
+
+		if result.err != nil {
+			writeErr = result.err
+			close(done)
+			continue
+		}
+
+		_, err = writer.Write(result.out)
+		if err != nil {
+			writeErr = fmt.Errorf("%w: writing to %q: %w", ErrConvert, outPath, err)
+			close(done)
+			continue
+		}
+
+		for field, n := range result.failures {
+			failures[field] += n
+		}
+		outcomes.Merge(&result.outcomes)
+		bar.IncrBy(int(result.bytesRead-lastRead), time.Since(start))
+		lastRead = result.bytesRead
+	}
+	workersWg.Wait()
+
+	if writeErr != nil {
+		return writeErr
+	}
+	if err = <-readErr; err != nil {
+		return err
+	}
+
+	printFailures(failures)
+
+	fmt.Println()
+	return outcomes.WriteTable(os.Stdout)
+}
+
+func toInPaths(inPath string) ([]string, error) {
+	stat, err := os.Stat(inPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConvert, err)
+	}
+
+	if !stat.IsDir() {
+		if !strings.HasSuffix(inPath, ".jsonl") && !strings.HasSuffix(inPath, ".jsonl.gz") {
+			return nil, fmt.Errorf("%w: got input file %q but want extension %q or %q", ErrConvert, inPath, ".jsonl", ".jsonl.gz")
+		}
+		return []string{inPath}, nil
+	}
+
+	entries, err := os.ReadDir(inPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading directory %q: %w", ErrConvert, inPath, err)
+	}
+
+	var inPaths []string
+	for _, entry := range entries {
+		if paperPattern.MatchString(entry.Name()) {
+			inPaths = append(inPaths, filepath.Join(inPath, entry.Name()))
+		}
+	}
+	if len(inPaths) == 0 {
+		return nil, fmt.Errorf("%w: no files in %q match %q", ErrConvert, inPath, paperPattern)
+	}
+	sort.Strings(inPaths)
+
+	return inPaths, nil
+}
+
+func readBatches(reader *bufio.Reader, countReader *fileio.BytesReadReader, batches chan<- batch, ordered chan<- chan *batchResult, done <-chan struct{}) error {
+	lines := make([][]byte, 0, batchSize)
+	send := func() bool {
+		results := make(chan *batchResult, 1)
+		select {
+		case ordered <- results:
+		case <-done:
+			return false
+		}
+		batches <- batch{lines: lines, bytesRead: countReader.BytesRead(), results: results}
+		lines = make([][]byte, 0, batchSize)
+		return true
+	}
+
 	for {
 		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
+			if !errors.Is(err, io.EOF) {
+				return fmt.Errorf("%w: reading input: %w", ErrConvert, err)
+			}
+			if len(lines) > 0 {
+				send()
 			}
-			return err
+			return nil
 		}
 
-		entry := &papers.PaperIdJson{}
-		err = json.Unmarshal(line, entry)
-		if err != nil {
-			return fmt.Errorf("%w: unmarshalling JSON: %w", ErrConvert, err)
+		if len(lines) == batchSize && !send() {
+			return nil
+		}
+	}
+}
+
+func convertBatch(lines [][]byte, opts options) *batchResult {
+	result := &batchResult{failures: make(map[string]int)}
+
+	for _, line := range lines {
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
 		}
 
-		idProto, err := entry.MarshalProto()
+		entry := &papers.PaperIdJson{}
+		err := json.Unmarshal(line, entry)
 		if err != nil {
-			return fmt.Errorf("%w: converting to proto: %w", ErrConvert, err)
+			result.failures[failureRecord]++
+			continue
 		}
 
-		if validate {
-			entry2 := &papers.PaperIdJson{}
-			err = entry2.UnmarshalProto(idProto)
-			if err != nil {
-				return err
+		fieldErrs := entry.Normalize(&result.outcomes)
+		idProto, protoErrs := entry.MarshalProtoPartial()
+		fieldErrs = append(fieldErrs, protoErrs...)
+		// Invalid identifiers are kept verbatim by Normalize, so numeric ones
+		// fail again in MarshalProtoPartial. Count each field once.
+		failed := make(map[string]bool)
+		for _, fieldErr := range fieldErrs {
+			if !failed[fieldErr.Field] {
+				failed[fieldErr.Field] = true
+				result.failures[fieldErr.Field]++
 			}
+		}
+		if idProto == nil {
+			result.failures[failureRecord]++
+			continue
+		}
 
-			if diff := cmp.Diff(entry, entry2, cmp.FilterPath(func(path cmp.Path) bool {
-				return path.Last().String() == ".License"
-			}, cmp.Comparer(func(left, right string) bool {
-				leftType, _ := papers.ToLicenseType(left)
-				rightType, _ := papers.ToLicenseType(right)
-				return leftType == rightType
-			}))); diff != "" {
-				return fmt.Errorf("%w: converting to proto and back is lossy: %s", ErrConvert, diff)
+		if opts.validate && len(fieldErrs) == 0 {
+			err = validateEntry(entry, idProto)
+			if err != nil {
+				result.err = err
+				return result
 			}
 		}
 
-		if !includeOaLink {
+		if !opts.includeOaLink {
 			idProto.OaLink = ""
 		}
 
 		protoBytes, err := proto.Marshal(idProto)
 		if err != nil {
-			return err
+			result.err = fmt.Errorf("%w: marshalling proto: %w", ErrConvert, err)
+			return result
 		}
 
-		nProtoBytes := len(protoBytes)
+		result.out = binary.AppendUvarint(result.out, uint64(len(protoBytes)))
+		result.out = append(result.out, protoBytes...)
+	}
 
-		var buf []byte
-		buf = binary.AppendUvarint(buf, uint64(nProtoBytes))
-		_, err = writer.Write(buf)
-		if err != nil {
-			return fmt.Errorf("%w: writing length to %q: %w", ErrConvert, outPath, err)
-		}
+	return result
+}
 
-		_, err = writer.Write(protoBytes)
-		if err != nil {
-			return fmt.Errorf("%w: writing proto to %q: %w", ErrConvert, outPath, err)
-		}
+func validateEntry(entry *papers.PaperIdJson, idProto *papers.PaperId) error {
+	entry2 := &papers.PaperIdJson{}
+	err := entry2.UnmarshalProto(idProto)
+	if err != nil {
+		return err
+	}
 
-		bar.IncrBy(len(line), time.Since(start))
+	if diff := cmp.Diff(entry, entry2, cmp.FilterPath(func(path cmp.Path) bool {
+		return path.Last().String() == ".License"
+	}, cmp.Comparer(func(left, right string) bool {
+		leftType, _ := papers.ToLicenseType(left)
+		rightType, _ := papers.ToLicenseType(right)
+		return leftType == rightType
+	}))); diff != "" {
+		return fmt.Errorf("%w: converting to proto and back is lossy: %s", ErrConvert, diff)
 	}
 
 	return nil
 }
+
+func printFailures(failures map[string]int) {
+	fields := make([]string, 0, len(failures))
+	for field := range failures {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	// Add newline to prevent the first line of output from being consumed by progress bar.
+	fmt.Println()
+	for _, field := range fields {
+		fmt.Printf("%s;%d\n", field, failures[field])
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb"
+	"github.com/vbauerster/mpb/decor"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const FlagSecondary = "secondary"
+
+func main() {
+	cmd.Flags().StringSlice(FlagSecondary, nil, "secondary indexes to build, any of [doi|pmid|pmcid]")
+
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:     "pbl-index FILE",
+	Short:   "Build sidecar indexes for random access to the PaperIds in a .pbl file",
+	Args:    cobra.ExactArgs(1),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrPblIndex = errors.New("indexing PaperIds")
+
+func runE(cmd *cobra.Command, args []string) error {
+	secondary, err := cmd.Flags().GetStringSlice(FlagSecondary)
+	if err != nil {
+		return err
+	}
+
+	indexes := []*papers.Index{papers.UUIDIndex}
+	for _, name := range secondary {
+		idx, found := papers.SecondaryIndexes[name]
+		if !found {
+			return fmt.Errorf("%w: unknown secondary index %q", ErrPblIndex, name)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	inPath := args[0]
+	if ext := filepath.Ext(inPath); ext != papers.PblExt {
+		return fmt.Errorf("%w: got file extension %q but want %q", ErrPblIndex, ext, papers.PblExt)
+	}
+
+	file, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("%w: opening %q: %w", ErrPblIndex, inPath, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: reading stats of %q: %w", ErrPblIndex, inPath, err)
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return fmt.Errorf("%w: getting terminal size: %w", ErrPblIndex, err)
+	}
+	p := mpb.New(mpb.WithWidth(width))
+	bar := p.AddBar(stats.Size(),
+		mpb.PrependDecorators(decor.AverageSpeed(decor.UnitKiB, "%.1f")),
+		mpb.AppendDecorators(decor.AverageETA(decor.ET_STYLE_GO)))
+
+	builder := papers.NewIndexBuilder(indexes...)
+	reader := papers.NewPblReader(file)
+
+	start := time.Now()
+	entry := &papers.PaperId{}
+	incrEvery := 1 << 10
+	lastOffset := int64(0)
+	for i := 1; ; i++ {
+		offset := reader.Offset()
+		err = reader.Read(entry)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %w", ErrPblIndex, err)
+		}
+
+		builder.Add(offset, entry)
+
+		if i%incrEvery == 0 {
+			bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+			lastOffset = reader.Offset()
+		}
+	}
+	bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+
+	err = builder.Write(inPath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPblIndex, err)
+	}
+
+	return nil
+}
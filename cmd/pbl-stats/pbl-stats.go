@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb"
+	"github.com/vbauerster/mpb/decor"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FlagFields     = "fields"
+	FlagFormat     = "format"
+	FlagOut        = "out"
+	FlagCpuProfile = "cpuprofile"
+)
+
+const (
+	present = "present"
+	absent  = "absent"
+)
+
+func main() {
+	cmd.Flags().StringSlice(FlagFields, []string{"license"}, "PaperId fields to histogram, as dot-separated proto field paths")
+	cmd.Flags().String(FlagFormat, "csv", "output format, one of [csv|json]")
+	cmd.Flags().String(FlagOut, "", "output file path (default: stdout)")
+	cmd.Flags().String(FlagCpuProfile, "", "write cpu profile to `file`")
+
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "pbl-stats FILE",
+	Short: "Histogram PaperId fields in a .pbl file",
+	Long: `Histogram PaperId fields in a .pbl file.
+
+Enum fields are counted by value name, repeated fields by each distinct
+element, numeric and boolean fields by value, and string and message fields by
+whether they are present. Each count is of entries, so an entry repeating an
+element counts once. For example, "license", "resources", "doi", "pmcid", and
+"pmcid.version". With more than one field, also counts how often each pair of
+values from different fields occur in the same entry.`,
+	Args:    cobra.ExactArgs(1),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrPblStats = errors.New("counting PaperId fields")
+
+// Count is the number of entries with a value.
+type Count struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// CoOccurrence is the number of entries with both a value of Field and a value
+// of OtherField.
+type CoOccurrence struct {
+	Field      string `json:"field"`
+	Value      string `json:"value"`
+	OtherField string `json:"other_field"`
+	OtherValue string `json:"other_value"`
+	Count      int    `json:"count"`
+}
+
+type Stats struct {
+	Entries       int                `json:"entries"`
+	Histograms    map[string][]Count `json:"histograms"`
+	CoOccurrences []CoOccurrence     `json:"co_occurrences,omitempty"`
+}
+
+type coKey struct {
+	i, j   int
+	vi, vj string
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	cpuprofile, err := cmd.Flags().GetString(FlagCpuProfile)
+	if err != nil {
+		return err
+	}
+	if cpuprofile != "" {
+		f, err := os.Create(cpuprofile)
+		if err != nil {
+			log.Fatal("could not create CPU profile: ", err)
+		}
+		defer func() {
+			err := f.Close()
+			if err != nil {
+				log.Fatal("could not close CPU profile: ", err)
+			}
+		}()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal("could not start CPU profile: ", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	fields, err := cmd.Flags().GetStringSlice(FlagFields)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: no fields specified", ErrPblStats)
+	}
+	for i, field := range fields {
+		if slices.Contains(fields[:i], field) {
+			return fmt.Errorf("%w: field %q is repeated", ErrPblStats, field)
+		}
+	}
+
+	format, err := cmd.Flags().GetString(FlagFormat)
+	if err != nil {
+		return err
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("%w: unknown format %q", ErrPblStats, format)
+	}
+
+	outPath, err := cmd.Flags().GetString(FlagOut)
+	if err != nil {
+		return err
+	}
+
+	paths := make([][]protoreflect.FieldDescriptor, len(fields))
+	for i, field := range fields {
+		paths[i], err = resolvePath(field)
+		if err != nil {
+			return err
+		}
+	}
+
+	inPath := args[0]
+	if ext := filepath.Ext(inPath); ext != papers.PblExt {
+		return fmt.Errorf("%w: got file extension %q but want %q", ErrPblStats, ext, papers.PblExt)
+	}
+
+	file, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("%w: opening %q: %w", ErrPblStats, inPath, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: reading stats of %q: %w", ErrPblStats, inPath, err)
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return fmt.Errorf("%w: getting terminal size: %w", ErrPblStats, err)
+	}
+	p := mpb.New(mpb.WithWidth(width))
+	bar := p.AddBar(stats.Size(),
+		mpb.BarRemoveOnComplete(),
+		mpb.PrependDecorators(decor.AverageSpeed(decor.UnitKiB, "%.1f")),
+		mpb.AppendDecorators(decor.AverageETA(decor.ET_STYLE_GO)))
+
+	histograms := make([]map[string]int, len(fields))
+	for i := range histograms {
+		histograms[i] = make(map[string]int)
+	}
+	coCounts := make(map[coKey]int)
+
+	reader := papers.NewPblReader(file)
+	start := time.Now()
+	entry := &papers.PaperId{}
+	values := make([][]string, len(fields))
+	incrEvery := 1 << 10
+	lastOffset := int64(0)
+	nEntries := 0
+	for {
+		// Safe to reuse entry in this case since we aren't passing it anywhere else.
+		err = reader.Read(entry)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %w", ErrPblStats, err)
+		}
+		nEntries++
+
+		for i, path := range paths {
+			values[i] = fieldValues(entry.ProtoReflect(), path, values[i][:0])
+			for _, v := range values[i] {
+				histograms[i][v]++
+			}
+		}
+
+		for i := range values {
+			for j := i + 1; j < len(values); j++ {
+				for _, vi := range values[i] {
+					for _, vj := range values[j] {
+						coCounts[coKey{i: i, j: j, vi: vi, vj: vj}]++
+					}
+				}
+			}
+		}
+
+		if nEntries%incrEvery == 0 {
+			bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+			lastOffset = reader.Offset()
+		}
+	}
+	bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+	p.Wait()
+
+	result := Stats{
+		Entries:    nEntries,
+		Histograms: make(map[string][]Count, len(fields)),
+	}
+	for i, field := range fields {
+		result.Histograms[field] = sortedCounts(histograms[i])
+	}
+	for k, count := range coCounts {
+		result.CoOccurrences = append(result.CoOccurrences, CoOccurrence{
+			Field:      fields[k.i],
+			Value:      k.vi,
+			OtherField: fields[k.j],
+			OtherValue: k.vj,
+			Count:      count,
+		})
+	}
+	sort.Slice(result.CoOccurrences, func(i, j int) bool {
+		left, right := result.CoOccurrences[i], result.CoOccurrences[j]
+		if left.Count != right.Count {
+			return left.Count > right.Count
+		}
+		return fmt.Sprint(left) < fmt.Sprint(right)
+	})
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		outFile, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("%w: creating %q: %w", ErrPblStats, outPath, err)
+		}
+		defer func() {
+			err := outFile.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}()
+		out = outFile
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	default:
+		return writeCsv(out, fields, result)
+	}
+}
+
+// resolvePath looks up each segment of a dot-separated path of PaperId fields.
+func resolvePath(path string) ([]protoreflect.FieldDescriptor, error) {
+	var result []protoreflect.FieldDescriptor
+	message := (&papers.PaperId{}).ProtoReflect().Descriptor()
+
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		if message == nil {
+			return nil, fmt.Errorf("%w: %q: %q is not a message", ErrPblStats, path, strings.Join(segments[:i], "."))
+		}
+
+		field := message.Fields().ByName(protoreflect.Name(segment))
+		if field == nil {
+			return nil, fmt.Errorf("%w: %q: unknown field %q of %s", ErrPblStats, path, segment, message.Name())
+		}
+		result = append(result, field)
+
+		message = field.Message()
+		if field.IsList() || field.IsMap() {
+			message = nil
+		}
+	}
+
+	return result, nil
+}
+
+// fieldValues appends the distinct histogram values of the field at path in
+// message.
+func fieldValues(message protoreflect.Message, path []protoreflect.FieldDescriptor, result []string) []string {
+	for _, field := range path[:len(path)-1] {
+		if !message.Has(field) {
+			return append(result, absent)
+		}
+		message = message.Get(field).Message()
+	}
+
+	field := path[len(path)-1]
+	value := message.Get(field)
+
+	switch {
+	case field.IsList():
+		list := value.List()
+		if list.Len() == 0 {
+			return append(result, absent)
+		}
+		start := len(result)
+		for i := range list.Len() {
+			result = append(result, scalarString(field, list.Get(i)))
+		}
+		slices.Sort(result[start:])
+		return append(result[:start], slices.Compact(result[start:])...)
+	case field.Kind() == protoreflect.MessageKind,
+		field.Kind() == protoreflect.StringKind,
+		field.Kind() == protoreflect.BytesKind:
+		if message.Has(field) {
+			return append(result, present)
+		}
+		return append(result, absent)
+	default:
+		return append(result, scalarString(field, value))
+	}
+}
+
+func scalarString(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		enumValue := field.Enum().Values().ByNumber(value.Enum())
+		if enumValue == nil {
+			return strconv.Itoa(int(value.Enum()))
+		}
+		return string(enumValue.Name())
+	case protoreflect.MessageKind, protoreflect.StringKind, protoreflect.BytesKind:
+		return present
+	default:
+		return value.String()
+	}
+}
+
+// sortedCounts orders counts by decreasing count, then by value.
+func sortedCounts(histogram map[string]int) []Count {
+	result := make([]Count, 0, len(histogram))
+	for value, count := range histogram {
+		result = append(result, Count{Value: value, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+
+	return result
+}
+
+func writeCsv(out io.Writer, fields []string, stats Stats) error {
+	writer := csv.NewWriter(out)
+
+	err := writer.Write([]string{"field", "value", "other_field", "other_value", "count"})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		for _, count := range stats.Histograms[field] {
+			err = writer.Write([]string{field, count.Value, "", "", strconv.Itoa(count.Count)})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, co := range stats.CoOccurrences {
+		err = writer.Write([]string{co.Field, co.Value, co.OtherField, co.OtherValue, strconv.Itoa(co.Count)})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"testing"
+)
+
+func TestFieldValues(t *testing.T) {
+	tcs := []struct {
+		name  string
+		field string
+		entry *papers.PaperId
+		want  []string
+	}{
+		{
+			// An entry repeating an element counts it once.
+			name:  "repeated",
+			field: "resources",
+			entry: &papers.PaperId{Resources: []papers.ResourceType{
+				papers.ResourceType_RESOURCE_PDF, papers.ResourceType_RESOURCE_JSON, papers.ResourceType_RESOURCE_PDF,
+			}},
+			want: []string{"prefix", "RESOURCE_JSON", "RESOURCE_PDF"},
+		},
+		{
+			name:  "empty repeated",
+			field: "resources",
+			entry: &papers.PaperId{},
+			want:  []string{"prefix", absent},
+		},
+		{
+			name:  "enum",
+			field: "license",
+			entry: &papers.PaperId{License: papers.LicenseType_LICENSE_CC_BY},
+			want:  []string{"prefix", "LICENSE_CC_BY"},
+		},
+		{
+			name:  "string",
+			field: "doi",
+			entry: &papers.PaperId{Doi: "10.1000/a"},
+			want:  []string{"prefix", present},
+		},
+		{
+			name:  "missing message",
+			field: "pmcid.version",
+			entry: &papers.PaperId{},
+			want:  []string{"prefix", absent},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := resolvePath(tc.field)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Values are appended after those of other fields.
+			got := fieldValues(tc.entry.ProtoReflect(), path, []string{"prefix"})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb"
+	"github.com/vbauerster/mpb/decor"
+	"github.com/willbeason/software-mentions/pkg/papers"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+const FlagFields = "fields"
+
+func main() {
+	cmd.Flags().StringSlice(FlagFields, nil, "JSON fields to include in the output (default: all)")
+
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:     "pbl-to-jsonl INFILE OUTFILE",
+	Short:   "Convert a .pbl file of PaperIds back to .jsonl or .jsonl.gz",
+	Args:    cobra.ExactArgs(2),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrConvert = errors.New("converting to JSON")
+
+func runE(cmd *cobra.Command, args []string) error {
+	fields, err := cmd.Flags().GetStringSlice(FlagFields)
+	if err != nil {
+		return err
+	}
+
+	knownFields := jsonFields()
+	for _, field := range fields {
+		if !slices.Contains(knownFields, field) {
+			return fmt.Errorf("%w: unknown field %q, must be one of %v", ErrConvert, field, knownFields)
+		}
+	}
+
+	inPath := args[0]
+	if ext := filepath.Ext(inPath); ext != papers.PblExt {
+		return fmt.Errorf("%w: got input file extension %q but want %q", ErrConvert, ext, papers.PblExt)
+	}
+
+	outPath := args[1]
+	if !strings.HasSuffix(outPath, ".jsonl") && !strings.HasSuffix(outPath, ".jsonl.gz") {
+		return fmt.Errorf("%w: got output file %q but want extension %q or %q", ErrConvert, outPath, ".jsonl", ".jsonl.gz")
+	}
+
+	file, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("%w: opening %q: %w", ErrConvert, inPath, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: reading stats of %q: %w", ErrConvert, inPath, err)
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return fmt.Errorf("%w: getting terminal size: %w", ErrConvert, err)
+	}
+	p := mpb.New(mpb.WithWidth(width))
+	bar := p.AddBar(stats.Size(),
+		mpb.PrependDecorators(decor.AverageSpeed(decor.UnitKiB, "%.1f")),
+		mpb.AppendDecorators(decor.AverageETA(decor.ET_STYLE_GO)))
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("%w: creating %q: %w", ErrConvert, outPath, err)
+	}
+	// On success outFile is closed below, where its error is returned. This
+	// only releases it after an error.
+	defer func() {
+		_ = outFile.Close()
+	}()
+
+	var out io.Writer = outFile
+	var gzipWriter *gzip.Writer
+	if strings.HasSuffix(outPath, ".gz") {
+		gzipWriter = gzip.NewWriter(outFile)
+		out = gzipWriter
+	}
+
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+
+	reader := papers.NewPblReader(file)
+	start := time.Now()
+	idProto := &papers.PaperId{}
+	incrEvery := 1 << 10
+	lastOffset := int64(0)
+	for i := 1; ; i++ {
+		err = reader.Read(idProto)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %w", ErrConvert, err)
+		}
+
+		entry := &papers.PaperIdJson{}
+		err = entry.UnmarshalProto(idProto)
+		if err != nil {
+			return fmt.Errorf("%w: converting from proto: %w", ErrConvert, err)
+		}
+
+		if len(fields) == 0 {
+			err = encoder.Encode(entry)
+		} else {
+			err = encodeProjection(encoder, entry, fields)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: writing to %q: %w", ErrConvert, outPath, err)
+		}
+
+		if i%incrEvery == 0 {
+			bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+			lastOffset = reader.Offset()
+		}
+	}
+	bar.IncrBy(int(reader.Offset()-lastOffset), time.Since(start))
+
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("%w: flushing %q: %w", ErrConvert, outPath, err)
+	}
+	if gzipWriter != nil {
+		err = gzipWriter.Close()
+		if err != nil {
+			return fmt.Errorf("%w: closing gzip stream %q: %w", ErrConvert, outPath, err)
+		}
+	}
+	err = outFile.Close()
+	if err != nil {
+		return fmt.Errorf("%w: closing %q: %w", ErrConvert, outPath, err)
+	}
+
+	return nil
+}
+
+// encodeProjection writes only the requested fields of entry. Fields which
+// PaperIdJson omits when empty are still omitted.
+func encodeProjection(encoder *json.Encoder, entry *papers.PaperIdJson, fields []string) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	err = json.Unmarshal(entryBytes, &all)
+	if err != nil {
+		return err
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, found := all[field]; found {
+			projected[field] = value
+		}
+	}
+
+	return encoder.Encode(projected)
+}
+
+// jsonFields lists the JSON keys of PaperIdJson.
+func jsonFields() []string {
+	t := reflect.TypeFor[papers.PaperIdJson]()
+
+	result := make([]string, t.NumField())
+	for i := range t.NumField() {
+		tag := t.Field(i).Tag.Get("json")
+		result[i], _, _ = strings.Cut(tag, ",")
+	}
+
+	return result
+}
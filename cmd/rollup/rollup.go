@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+const (
+	FlagSource         = "source"
+	FlagSoftwareColumn = "software-column"
+	FlagAggregate      = "aggregate"
+	FlagThreshold      = "threshold"
+)
+
+func init() {
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions and purpose_assessments tables")
+	cmd.Flags().String(FlagSoftwareColumn, tables.SoftwareCanonicalFieldName, "mentions column identifying the software")
+	cmd.Flags().StringSlice(FlagAggregate, []string{"max", "mean"},
+		fmt.Sprintf("aggregations of the certainty scores of each purpose and scope, of %v", tables.Aggregations))
+	cmd.Flags().Float64(FlagThreshold, 0.5, "certainty_score the mentions counted by the count aggregation must be above")
+}
+
+func main() {
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "rollup IN_DIR OUT_DIR",
+	Short: "Aggregate purpose assessments into a paper_software table",
+	Long: `Aggregate purpose assessments into a paper_software table.
+
+Writes a row to the paper_software table in OUT_DIR for each paper and software
+it mentions, with the number of mentions, the index of the first mention, and
+for each purpose and scope the --aggregate aggregations of the certainty scores
+of the mentions' purpose assessments. For example, with the default
+aggregations, used_document_max is the largest document-scope certainty that
+the paper used the software.
+
+The count aggregation counts the mentions with a certainty score above
+--threshold, as in the usual certainty_score > 0.5.
+
+If the mentions table has purpose columns, as when mentions are extracted with
+--purpose-layout=wide, the certainty scores are read from them instead of from
+the purpose_assessments table.`,
+	Args:    cobra.ExactArgs(2),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrRollup = errors.New("rolling up purpose assessments")
+
+type paperSoftware struct {
+	paper    uint32
+	software uint32
+}
+
+// certainty aggregates the certainty scores of one purpose and scope.
+type certainty struct {
+	n     uint32
+	sum   float64
+	min   float64
+	max   float64
+	count uint32
+}
+
+func (c *certainty) add(score, threshold float64) {
+	if c.n == 0 || score < c.min {
+		c.min = score
+	}
+	if c.n == 0 || score > c.max {
+		c.max = score
+	}
+	c.n++
+	c.sum += score
+	if score > threshold {
+		c.count++
+	}
+}
+
+func (c *certainty) value(aggregation string) float64 {
+	switch aggregation {
+	case "max":
+		return c.max
+	case "mean":
+		return c.sum / float64(c.n)
+	case "min":
+		return c.min
+	default:
+		panic("invalid aggregation " + aggregation)
+	}
+}
+
+type rollup struct {
+	mentions          uint32
+	firstMentionIndex uint16
+	// certainties are indexed by purpose, then scope, in the order of
+	// tables.Purposes and tables.Scopes.
+	certainties []certainty
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inDir := args[0]
+	outDir := args[1]
+
+	source, err := cmd.Flags().GetString(FlagSource)
+	if err != nil {
+		return err
+	}
+	softwareColumn, err := cmd.Flags().GetString(FlagSoftwareColumn)
+	if err != nil {
+		return err
+	}
+	aggregations, err := cmd.Flags().GetStringSlice(FlagAggregate)
+	if err != nil {
+		return err
+	}
+	threshold, err := cmd.Flags().GetFloat64(FlagThreshold)
+	if err != nil {
+		return err
+	}
+
+	for i, aggregation := range aggregations {
+		if !slices.Contains(tables.Aggregations, aggregation) {
+			return fmt.Errorf("%w: unknown aggregation %q, want one of %v", ErrRollup, aggregation, tables.Aggregations)
+		}
+		if slices.Contains(aggregations[:i], aggregation) {
+			return fmt.Errorf("%w: aggregation %q is repeated", ErrRollup, aggregation)
+		}
+	}
+
+	err = os.MkdirAll(outDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("%w: creating output directory: %w", ErrRollup, err)
+	}
+
+	purposes, err := subsets.OpenPurposes(inDir, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRollup, err)
+	}
+
+	var softwareNames []string
+	softwareIds := make(map[string]uint32)
+	rollups := make(map[paperSoftware]*rollup)
+	mentionRollups := make(map[string]*rollup)
+
+	columns := []string{tables.SoftwareMentionIdFieldName, tables.PaperIdFieldName, tables.MentionIndexFieldName, softwareColumn}
+	err = subsets.Scan(ctx, purposes.MentionsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := subsets.Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		mentionIndexColumn, err := subsets.Column[*array.Uint16](record, tables.MentionIndexFieldName)
+		if err != nil {
+			return err
+		}
+		softwareColumn, err := subsets.Column[*array.String](record, softwareColumn)
+		if err != nil {
+			return err
+		}
+
+		for i, paperId := range paperIdColumn.Uint32Values() {
+			if softwareColumn.IsNull(i) || softwareColumn.Value(i) == "" {
+				continue
+			}
+			software := softwareColumn.Value(i)
+
+			softwareId, found := softwareIds[software]
+			if !found {
+				softwareId = uint32(len(softwareNames))
+				softwareIds[software] = softwareId
+				softwareNames = append(softwareNames, software)
+			}
+
+			key := paperSoftware{paper: paperId, software: softwareId}
+			r, found := rollups[key]
+			mentionIndex := mentionIndexColumn.Value(i)
+			if !found {
+				r = &rollup{
+					firstMentionIndex: mentionIndex,
+					certainties:       make([]certainty, len(tables.Purposes)*len(tables.Scopes)),
+				}
+				rollups[key] = r
+			}
+			r.mentions++
+			r.firstMentionIndex = min(r.firstMentionIndex, mentionIndex)
+
+			mentionRollups[mentionIdColumn.Value(i)] = r
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading mentions: %w", ErrRollup, err)
+	}
+
+	err = purposes.ScanScores(ctx, func(mentionId, scope, purpose string, score float64) error {
+		r, found := mentionRollups[mentionId]
+		if !found {
+			return nil
+		}
+
+		purposeIndex := slices.Index(tables.Purposes, purpose)
+		scopeIndex := slices.Index(tables.Scopes, scope)
+		if purposeIndex < 0 || scopeIndex < 0 {
+			return fmt.Errorf("unknown purpose %q or scope %q", purpose, scope)
+		}
+
+		r.certainties[purposeIndex*len(tables.Scopes)+scopeIndex].add(score, threshold)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading purpose assessments: %w", ErrRollup, err)
+	}
+
+	keys := make([]paperSoftware, 0, len(rollups))
+	for key := range rollups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].paper != keys[j].paper {
+			return keys[i].paper < keys[j].paper
+		}
+		return softwareNames[keys[i].software] < softwareNames[keys[j].software]
+	})
+
+	outPath := filepath.Join(outDir, tables.PaperSoftwareName+"."+source+tables.ParquetExt)
+	err = writeRollups(outPath, aggregations, softwareNames, keys, rollups)
+	if err != nil {
+		return fmt.Errorf("%w: writing %q: %w", ErrRollup, outPath, err)
+	}
+
+	fmt.Printf("papers and software: %d\n", len(keys))
+
+	return nil
+}
+
+func writeRollups(outPath string, aggregations, softwareNames []string, keys []paperSoftware, rollups map[paperSoftware]*rollup) error {
+	schema := tables.PaperSoftware(aggregations)
+
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer recordBuilder.Release()
+
+	fields := recordBuilder.Fields()
+	paperIdField := fields[0].(*array.Uint32Builder)
+	softwareField := fields[1].(*array.StringBuilder)
+	mentionsField := fields[2].(*array.Uint32Builder)
+	firstMentionIndexField := fields[3].(*array.Uint16Builder)
+	certaintyFields := fields[4:]
+
+	for _, key := range keys {
+		r := rollups[key]
+
+		paperIdField.Append(key.paper)
+		softwareField.Append(softwareNames[key.software])
+		mentionsField.Append(r.mentions)
+		firstMentionIndexField.Append(r.firstMentionIndex)
+
+		// Certainty columns are ordered by purpose, scope, then aggregation, as
+		// are r.certainties without the aggregations.
+		for i, field := range certaintyFields {
+			c := r.certainties[i/len(aggregations)]
+			aggregation := aggregations[i%len(aggregations)]
+
+			if aggregation == "count" {
+				field.(*array.Uint32Builder).Append(c.count)
+				continue
+			}
+			if c.n == 0 {
+				field.AppendNull()
+				continue
+			}
+			field.(*array.Float64Builder).Append(c.value(aggregation))
+		}
+	}
+
+	return subsets.WriteRecord(outPath, schema, recordBuilder)
+}
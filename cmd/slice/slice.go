@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"os"
+	"path/filepath"
+)
+
+const (
+	FlagFilter = "filter"
+	FlagSource = "source"
+)
+
+// MetadataFilter is the key of the Parquet key-value metadata recording the
+// filter a slice was made with.
+const MetadataFilter = "slice.filter"
+
+func init() {
+	cmd.Flags().String(FlagFilter, "", "filter expression over papers, mentions, and purpose_assessments")
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions and purpose_assessments tables")
+}
+
+func main() {
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "slice IN_DIR OUT_DIR",
+	Short: "extracts the papers, mentions, and purpose assessments matching a filter",
+	Long: `extracts the papers, mentions, and purpose assessments matching a filter.
+
+The filter is a conjunction of comparisons TABLE.COLUMN OP VALUE, where TABLE is
+one of papers, mentions, or purpose_assessments and OP is one of ==, !=, <, <=,
+>, >=, or in. For example,
+
+  papers.published_year >= 2015 && papers.published_year <= 2020 &&
+  mentions.software_normalized in ("R", "Python") &&
+  purpose_assessments.scope == "document" &&
+  purpose_assessments.purpose == "used" &&
+  purpose_assessments.certainty_score > 0.5
+
+A mention matches if it matches the mentions comparisons and one of its purpose
+assessments matches all the purpose_assessments comparisons. A paper matches if
+it matches the papers comparisons and, if the filter has mentions or
+purpose_assessments comparisons, one of its mentions matches. The output has
+the matching papers, the matching mentions of those papers, and every purpose
+assessment of those mentions, so every mention's paper and every assessment's
+mention is present.
+
+Mentions extracted with --purpose-layout=wide have their purpose assessments as
+columns, so filter them with mentions comparisons such as
+mentions.used_document > 0.5.`,
+	Args:    cobra.ExactArgs(2),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrSlice = errors.New("slicing tables")
+
+func runE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inDir := args[0]
+	outDir := args[1]
+
+	filterString, err := cmd.Flags().GetString(FlagFilter)
+	if err != nil {
+		return err
+	}
+	if filterString == "" {
+		return fmt.Errorf("%w: --%s is required", ErrSlice, FlagFilter)
+	}
+	filter, err := subsets.ParseFilter(filterString)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSlice, err)
+	}
+	for _, table := range filter.Tables() {
+		switch table {
+		case tables.PapersName, tables.MentionsName, tables.PurposeAssessmentsName:
+		default:
+			return fmt.Errorf("%w: unknown table %q", ErrSlice, table)
+		}
+	}
+
+	source, err := cmd.Flags().GetString(FlagSource)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(outDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("%w: creating output directory: %w", ErrSlice, err)
+	}
+
+	papersName := tables.PapersName + tables.ParquetExt
+	mentionsName := tables.MentionsName + "." + source + tables.ParquetExt
+	assessmentsName := tables.PurposeAssessmentsName + "." + source + tables.ParquetExt
+
+	purposes, err := subsets.OpenPurposes(inDir, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSlice, err)
+	}
+
+	papersPredicate := filter.Predicate(tables.PapersName)
+	mentionsPredicate := filter.Predicate(tables.MentionsName)
+	assessmentsPredicate := filter.Predicate(tables.PurposeAssessmentsName)
+	if purposes.Wide && len(assessmentsPredicate) > 0 {
+		return fmt.Errorf("%w: %q has purpose assessments as columns, so filter them with mentions comparisons such as mentions.%s > 0.5",
+			ErrSlice, mentionsName, tables.PurposeScopeFieldName(tables.Purposes[0], tables.Scopes[0]))
+	}
+	filterMentions := len(mentionsPredicate) > 0 || len(assessmentsPredicate) > 0
+
+	// Mentions with a matching purpose assessment.
+	var assessedMentions map[string]struct{}
+	if len(assessmentsPredicate) > 0 {
+		assessedMentions, err = subsets.MatchingMentions(ctx, purposes.AssessmentsPath, assessmentsPredicate)
+		if err != nil {
+			return fmt.Errorf("%w: filtering purpose assessments: %w", ErrSlice, err)
+		}
+	}
+
+	// Matching mentions, and the papers they are in.
+	var mentionPapers map[string]uint32
+	var papersWithMentions *subsets.Bitset
+	if filterMentions {
+		mentionPapers = make(map[string]uint32)
+		papersWithMentions = &subsets.Bitset{}
+		columns := append([]string{tables.SoftwareMentionIdFieldName, tables.PaperIdFieldName}, mentionsPredicate.Columns()...)
+		err = subsets.Scan(ctx, filepath.Join(inDir, mentionsName), columns, func(record arrow.Record) error {
+			idColumn, err := subsets.Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+			if err != nil {
+				return err
+			}
+			paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+			if err != nil {
+				return err
+			}
+			match, err := mentionsPredicate.Bind(record)
+			if err != nil {
+				return err
+			}
+
+			for i := range idColumn.Len() {
+				if !match(i) {
+					continue
+				}
+
+				id := idColumn.Value(i)
+				if assessedMentions != nil {
+					if _, found := assessedMentions[id]; !found {
+						continue
+					}
+				}
+
+				mentionPapers[id] = paperIdColumn.Value(i)
+				papersWithMentions.Add(paperIdColumn.Value(i))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%w: filtering mentions: %w", ErrSlice, err)
+		}
+	}
+
+	// Matching papers.
+	paperIds := &subsets.Bitset{}
+	columns := append([]string{tables.PaperIdFieldName}, papersPredicate.Columns()...)
+	err = subsets.Scan(ctx, filepath.Join(inDir, papersName), columns, func(record arrow.Record) error {
+		idColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		match, err := papersPredicate.Bind(record)
+		if err != nil {
+			return err
+		}
+
+		for i, id := range idColumn.Uint32Values() {
+			if !match(i) {
+				continue
+			}
+			if papersWithMentions != nil && !papersWithMentions.Contains(id) {
+				continue
+			}
+
+			paperIds.Add(id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: filtering papers: %w", ErrSlice, err)
+	}
+
+	writeOptions := subsets.WriteOptions{Metadata: []map[string]string{{MetadataFilter: filterString}}}
+	paperSelector := subsets.PaperSelector{paperIds}
+
+	// Without mention comparisons, every mention of a matching paper matches.
+	var mentionSelector subsets.Selector = paperSelector
+	nMentions := -1
+	if filterMentions {
+		mentionIds := make(map[string]struct{})
+		for id, paperId := range mentionPapers {
+			if paperIds.Contains(paperId) {
+				mentionIds[id] = struct{}{}
+			}
+		}
+		mentionSelector = subsets.MentionSelector{mentionIds}
+		nMentions = len(mentionIds)
+	}
+
+	fmt.Printf("papers: %d\n", paperIds.Len())
+	if nMentions >= 0 {
+		fmt.Printf("mentions: %d\n", nMentions)
+	}
+
+	err = subsets.Write(ctx, filepath.Join(inDir, papersName), []string{filepath.Join(outDir, papersName)}, paperSelector, writeOptions)
+	if err != nil {
+		return fmt.Errorf("%w: writing papers: %w", ErrSlice, err)
+	}
+
+	err = subsets.Write(ctx, filepath.Join(inDir, mentionsName), []string{filepath.Join(outDir, mentionsName)}, mentionSelector, writeOptions)
+	if err != nil {
+		return fmt.Errorf("%w: writing mentions: %w", ErrSlice, err)
+	}
+
+	if purposes.Wide {
+		return nil
+	}
+
+	err = subsets.Write(ctx, purposes.AssessmentsPath, []string{filepath.Join(outDir, assessmentsName)}, mentionSelector, writeOptions)
+	if err != nil {
+		return fmt.Errorf("%w: writing purpose assessments: %w", ErrSlice, err)
+	}
+
+	return nil
+}
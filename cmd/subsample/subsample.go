@@ -1,40 +1,70 @@
 package main
 
 import (
-	"compress/gzip"
 	"context"
-	"errors"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"github.com/apache/arrow/go/v18/arrow"
 	"github.com/apache/arrow/go/v18/arrow/array"
-	"github.com/apache/arrow/go/v18/arrow/memory"
-	"github.com/apache/arrow/go/v18/parquet"
-	"github.com/apache/arrow/go/v18/parquet/compress"
-	"github.com/apache/arrow/go/v18/parquet/file"
-	"github.com/apache/arrow/go/v18/parquet/pqarrow"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/willbeason/software-mentions/pkg/subsets"
 	"github.com/willbeason/software-mentions/pkg/tables"
-	"io"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	batchSize = 1 << 20
+	FlagPartitions = "partitions"
+	FlagSeed       = "seed"
+	FlagMethod     = "method"
+	FlagSalt       = "salt"
+
+	FlagStratifyBy             = "stratify-by"
+	FlagQuotas                 = "quotas"
+	FlagIncludeWithoutMentions = "include-without-mentions"
+	FlagRowGroupSize           = "row-group-size"
 )
 
 const (
-	FlagPartitions = "partitions"
-	FlagSeed       = "seed"
+	MethodRandom = "random"
+	MethodHash   = "hash"
 )
 
+// Keys of the Parquet key-value metadata recording how a partition was sampled.
+const (
+	MetadataMethod   = "subsample.method"
+	MetadataFraction = "subsample.fraction"
+	MetadataSeed     = "subsample.seed"
+	MetadataSalt     = "subsample.salt"
+
+	MetadataStratifyBy             = "subsample.stratify_by"
+	MetadataQuota                  = "subsample.quota"
+	MetadataIncludeWithoutMentions = "subsample.include_without_mentions"
+)
+
+const samplingReportName = "sampling_report.csv"
+
 func init() {
 	cmd.Flags().Float64Slice(FlagPartitions, []float64{0.01, 0.05}, "dataset partitions")
 	cmd.Flags().Int64(FlagSeed, 0, "random seed")
+	cmd.Flags().String(FlagMethod, MethodRandom, "sampling method, one of [random|hash]")
+	cmd.Flags().String(FlagSalt, "softcite", "salt of the keyed hash for the hash sampling method")
+	cmd.Flags().StringSlice(FlagStratifyBy, nil, "papers columns to stratify sampling by, such as published_year or genre")
+	cmd.Flags().IntSlice(FlagQuotas, nil, "papers per stratum in each partition, instead of proportional allocation")
+	cmd.Flags().Bool(FlagIncludeWithoutMentions, false, "also sample papers without software mentions")
+	cmd.Flags().Int64(FlagRowGroupSize, subsets.DefaultRowGroupSize, "rows per row group of the output files")
 }
 
 func main() {
@@ -46,8 +76,25 @@ func main() {
 }
 
 var cmd = cobra.Command{
-	Use:     "subsample IN_DIR OUT_DIR",
-	Short:   "subsamples the SoftCite dataset",
+	Use:   "subsample IN_DIR OUT_DIR",
+	Short: "subsamples the SoftCite dataset",
+	Long: `subsamples the SoftCite dataset.
+
+With the random method, partitions are disjoint and each paper is drawn in file
+order from a seeded random number generator.
+
+With the hash method, a paper is in each partition whose fraction exceeds a
+keyed hash of its softcite_id, so partitions are nested and do not depend on
+the order of the papers table. For example, "--partitions=0.01,0.05,0.1"
+produces a 1% subset contained in a 5% subset contained in a 10% subset, which
+are the same for every release sampled with the same salt.
+
+With --stratify-by, papers are grouped by their values of the given columns and
+each group is sampled separately, either in proportion to its size or up to the
+per-partition --quotas. With the hash method, quotas may not decrease as the
+partition fraction increases, so that partitions stay nested. Stratified
+sampling writes sampling_report.csv, comparing the distribution of each column
+in each partition to that of all papers, including those without mentions.`,
 	Args:    cobra.ExactArgs(2),
 	Version: "0.1.0",
 	RunE:    runE,
@@ -69,43 +116,129 @@ func runE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("getting partitions: %w", err)
 	}
 
-	thresholds := make([]float64, len(partitions))
-	sum := 0.0
+	method, err := cmd.Flags().GetString(FlagMethod)
+	if err != nil {
+		return fmt.Errorf("getting method: %w", err)
+	}
+
+	metadata := make([]map[string]string, len(partitions))
 	for i, partition := range partitions {
-		sum += partition
-		thresholds[i] = sum
+		metadata[i] = map[string]string{
+			MetadataMethod:   method,
+			MetadataFraction: strconv.FormatFloat(partition, 'g', -1, 64),
+		}
+	}
+
+	opts := sampleOptions{Partitions: partitions}
+
+	opts.StratifyBy, err = cmd.Flags().GetStringSlice(FlagStratifyBy)
+	if err != nil {
+		return fmt.Errorf("getting stratify-by columns: %w", err)
+	}
+
+	opts.Quotas, err = cmd.Flags().GetIntSlice(FlagQuotas)
+	if err != nil {
+		return fmt.Errorf("getting quotas: %w", err)
+	}
+	if len(opts.Quotas) == 0 {
+		opts.Quotas = nil
+	} else if len(opts.StratifyBy) == 0 {
+		return fmt.Errorf("--%s requires --%s", FlagQuotas, FlagStratifyBy)
+	} else if len(opts.Quotas) != len(partitions) {
+		return fmt.Errorf("got %d quotas but %d partitions", len(opts.Quotas), len(partitions))
+	} else if method == MethodHash {
+		err = checkNestedQuotas(partitions, opts.Quotas)
+		if err != nil {
+			return err
+		}
 	}
 
-	seed, err := getSeed(cmd)
+	opts.IncludeWithoutMentions, err = cmd.Flags().GetBool(FlagIncludeWithoutMentions)
 	if err != nil {
-		return fmt.Errorf("getting seed: %w", err)
+		return fmt.Errorf("getting include without mentions: %w", err)
+	}
+
+	for i := range metadata {
+		metadata[i][MetadataIncludeWithoutMentions] = strconv.FormatBool(opts.IncludeWithoutMentions)
+		if len(opts.StratifyBy) > 0 {
+			metadata[i][MetadataStratifyBy] = strings.Join(opts.StratifyBy, ",")
+		}
+		if opts.Quotas != nil {
+			metadata[i][MetadataQuota] = strconv.Itoa(opts.Quotas[i])
+		}
+	}
+
+	var sampler Sampler
+	switch method {
+	case MethodRandom:
+		seed, err := getSeed(cmd)
+		if err != nil {
+			return fmt.Errorf("getting seed: %w", err)
+		}
+		for i := range metadata {
+			metadata[i][MetadataSeed] = strconv.FormatInt(seed, 10)
+		}
+		sampler = newRandomSampler(seed, partitions)
+	case MethodHash:
+		salt, err := cmd.Flags().GetString(FlagSalt)
+		if err != nil {
+			return fmt.Errorf("getting salt: %w", err)
+		}
+		for i := range metadata {
+			metadata[i][MetadataSalt] = salt
+		}
+		sampler = newHashSampler(salt, partitions)
+	default:
+		return fmt.Errorf("unknown sampling method %q", method)
 	}
+
 	inPapers := filepath.Join(inPath, tables.PapersName+tables.ParquetExt)
-	paperPartitions, err := getPartitions(ctx, seed, inPapers, thresholds)
+	paperPartitions, strata, err := getPartitions(ctx, sampler, inPapers, opts)
 	if err != nil {
 		return fmt.Errorf("getting paper partitions: %w", err)
 	}
 
+	if len(opts.StratifyBy) > 0 {
+		err = writeSamplingReport(filepath.Join(outDir, samplingReportName), opts.StratifyBy, strata, paperPartitions)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, partition := range paperPartitions {
-		fmt.Println(len(partition))
+		fmt.Println(partition.Len())
+	}
+
+	writeOptions := subsets.WriteOptions{Metadata: metadata}
+	writeOptions.RowGroupSize, err = cmd.Flags().GetInt64(FlagRowGroupSize)
+	if err != nil {
+		return fmt.Errorf("getting row group size: %w", err)
 	}
 
 	outPapers := filepath.Join(outDir, tables.PapersName+tables.ParquetExt)
-	err = partitionParquet(ctx, inPapers, outPapers, paperPartitions)
+	err = partitionParquet(ctx, inPapers, outPapers, paperPartitions, writeOptions)
 	if err != nil {
 		return fmt.Errorf("partitioning papers: %w", err)
 	}
 
 	inMentions := filepath.Join(inPath, tables.MentionsName+".pdf"+tables.ParquetExt)
 	outMentions := filepath.Join(outDir, tables.MentionsName+".pdf"+tables.ParquetExt)
-	err = partitionParquet(ctx, inMentions, outMentions, paperPartitions)
+	err = partitionParquet(ctx, inMentions, outMentions, paperPartitions, writeOptions)
 	if err != nil {
 		return fmt.Errorf("partitioning mentions: %w", err)
 	}
 
-	inAssessments := filepath.Join(inPath, tables.PurposeAssessmentsName+".pdf"+tables.ParquetExt)
+	purposes, err := subsets.OpenPurposes(inPath, "pdf")
+	if err != nil {
+		return fmt.Errorf("reading purpose assessments: %w", err)
+	}
+	if purposes.Wide {
+		// The mentions just written carry their purpose assessments.
+		return nil
+	}
+
 	outAssessments := filepath.Join(outDir, tables.PurposeAssessmentsName+".pdf"+tables.ParquetExt)
-	err = partitionParquet(ctx, inAssessments, outAssessments, paperPartitions)
+	err = partitionParquet(ctx, purposes.AssessmentsPath, outAssessments, paperPartitions, writeOptions)
 	if err != nil {
 		return fmt.Errorf("partitioning assessments: %w", err)
 	}
@@ -113,204 +246,373 @@ func runE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func partitionParquet(ctx context.Context, inPath, outPath string, partitions []map[uint32]struct{}) error {
-	allocator := memory.NewGoAllocator()
-	inFileReader, err := file.OpenParquetFile(inPath, true)
-	if err != nil {
-		return fmt.Errorf("opening parquet file %q: %w", inPath, err)
+// partitionParquet writes the rows of each partition of papers in inPath to
+// numbered files next to outPath.
+func partitionParquet(ctx context.Context, inPath, outPath string, partitions []*subsets.Bitset, opts subsets.WriteOptions) error {
+	ext := filepath.Ext(outPath)
+	outPaths := make([]string, len(partitions))
+	for i := range partitions {
+		outPaths[i] = fmt.Sprintf("%s_%d%s", outPath[:len(outPath)-len(ext)], i, ext)
 	}
 
-	inReader, err := pqarrow.NewFileReader(inFileReader,
-		pqarrow.ArrowReadProperties{Parallel: true, BatchSize: batchSize},
-		allocator,
-	)
-	if err != nil {
-		return fmt.Errorf("creating pqarrow FileReader: %w", err)
+	return subsets.Write(ctx, inPath, outPaths, subsets.PaperSelector(partitions), opts)
+}
+
+// Sampler assigns papers to partitions.
+type Sampler interface {
+	// Sample appends the partitions which include the paper to dst.
+	Sample(softciteId string, dst []int) []int
+	// Rank returns a value in [0, 1) ordering the paper within its stratum.
+	Rank(softciteId string) float64
+	// Nested reports whether each partition includes the partitions with
+	// smaller fractions.
+	Nested() bool
+}
+
+// randomSampler assigns each paper to at most one partition, drawing from rng
+// in the order papers are sampled.
+type randomSampler struct {
+	rng        *rand.Rand
+	thresholds []float64
+}
+
+func newRandomSampler(seed int64, partitions []float64) *randomSampler {
+	thresholds := make([]float64, len(partitions))
+	sum := 0.0
+	for i, partition := range partitions {
+		sum += partition
+		thresholds[i] = sum
 	}
 
-	recordReader, err := inReader.GetRecordReader(ctx, nil, nil)
-	if err != nil {
-		return fmt.Errorf("getting record reader: %w", err)
+	return &randomSampler{
+		rng:        rand.New(rand.NewSource(seed)),
+		thresholds: thresholds,
 	}
+}
 
-	paperSchema, err := inReader.Schema()
-	if err != nil {
-		return fmt.Errorf("getting schema: %w", err)
+func (s *randomSampler) Sample(softciteId string, dst []int) []int {
+	randValue := s.Rank(softciteId)
+	for i, threshold := range s.thresholds {
+		if randValue < threshold {
+			return append(dst, i)
+		}
 	}
+	return dst
+}
 
-	outPaperFiles := make([]string, len(partitions))
-	writers := make([]*pqarrow.FileWriter, len(partitions))
-	recordBuilders := make([]*array.RecordBuilder, len(partitions))
-	for i := range partitions {
-		ext := filepath.Ext(outPath)
-		outPathI := fmt.Sprintf("%s_%d%s", outPath[:len(outPath)-len(ext)], i, ext)
-		outPapersFile, err := os.Create(outPathI)
-		outPaperFiles[i] = outPathI
+func (s *randomSampler) Rank(_ string) float64 {
+	return s.rng.Float64()
+}
 
-		if err != nil {
-			return fmt.Errorf("creating paper ids file %q: %w", outPath, err)
-		}
-		writer, err := pqarrow.NewFileWriter(
-			paperSchema,
-			outPapersFile,
-			parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Gzip), parquet.WithCompressionLevel(gzip.BestCompression)),
-			pqarrow.DefaultWriterProps(),
-		)
-		if err != nil {
-			return fmt.Errorf("creating writer: %w", err)
+func (s *randomSampler) Nested() bool {
+	return false
+}
+
+// hashSampler assigns each paper to every partition whose fraction exceeds the
+// keyed hash of its softcite_id, scaled to [0, 1).
+type hashSampler struct {
+	salt       []byte
+	partitions []float64
+}
+
+func newHashSampler(salt string, partitions []float64) *hashSampler {
+	return &hashSampler{
+		salt:       []byte(salt),
+		partitions: partitions,
+	}
+}
+
+func (s *hashSampler) Sample(softciteId string, dst []int) []int {
+	value := s.Rank(softciteId)
+	for i, partition := range s.partitions {
+		if value < partition {
+			dst = append(dst, i)
 		}
-		writers[i] = writer
+	}
+	return dst
+}
 
-		defer func() {
-			err := writer.Close()
-			if err != nil {
-				fmt.Println(err)
-			}
-		}()
+func (s *hashSampler) Rank(softciteId string) float64 {
+	return hashUnit(s.salt, softciteId)
+}
+
+func (s *hashSampler) Nested() bool {
+	return true
+}
 
-		recordBuilder := array.NewRecordBuilder(allocator, paperSchema)
-		defer recordBuilder.Release()
-		recordBuilders[i] = recordBuilder
+// hashUnit maps softciteId to [0, 1) with HMAC-SHA256 keyed by salt.
+func hashUnit(salt []byte, softciteId string) float64 {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(softciteId))
+	sum := mac.Sum(nil)
+
+	// Use the top 53 bits, the precision of a float64.
+	return float64(binary.BigEndian.Uint64(sum)>>11) / (1 << 53)
+}
+
+// checkNestedQuotas returns an error if a partition has a smaller quota than a
+// partition with a smaller fraction, since the hash method samples each
+// partition from the lowest ranked papers and so could not nest them.
+func checkNestedQuotas(partitions []float64, quotas []int) error {
+	order := make([]int, len(partitions))
+	for i := range order {
+		order[i] = i
 	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return partitions[order[a]] < partitions[order[b]]
+	})
 
-	var paperIdFieldIndex int
-	for i, field := range paperSchema.Fields() {
-		if field.Name == tables.PaperIdFieldName {
-			paperIdFieldIndex = i
-			break
+	for k := 1; k < len(order); k++ {
+		smaller, larger := order[k-1], order[k]
+		if quotas[larger] < quotas[smaller] {
+			return fmt.Errorf("--%s of partition %g is %d, less than %d of partition %g; "+
+				"quotas may not decrease with partition fraction for the %s method",
+				FlagQuotas, partitions[larger], quotas[larger], quotas[smaller], partitions[smaller], MethodHash)
 		}
 	}
 
-	var record arrow.Record
-	for record, err = recordReader.Read(); err == nil; record, err = recordReader.Read() {
-		idColumn, ok := record.Column(paperIdFieldIndex).(*array.Uint32)
-		if !ok {
-			return fmt.Errorf("expected paper id column to be of type *array.Uint32, got %T", record.Column(paperIdFieldIndex))
+	return nil
+}
+
+// sampleOptions configures which papers are sampled and how.
+type sampleOptions struct {
+	// Partitions are the fractions of papers in each partition.
+	Partitions []float64
+	// StratifyBy are the papers columns whose distinct combinations of values
+	// are sampled separately. If empty, papers are sampled independently.
+	StratifyBy []string
+	// Quotas, if set, are the number of papers of each stratum in each
+	// partition instead of the stratum's share of the partition fraction.
+	Quotas []int
+	// IncludeWithoutMentions samples papers with no software mentions.
+	IncludeWithoutMentions bool
+}
+
+// stratum is the papers with the same values of the stratify-by columns.
+type stratum struct {
+	values []string
+	// population is the number of papers in the stratum, including those not
+	// eligible for sampling.
+	population int
+	// papers are the papers eligible for sampling.
+	papers []rankedPaper
+}
+
+type rankedPaper struct {
+	id   uint32
+	rank float64
+}
+
+// allocate adds the papers of s to each partition, taking the lowest ranked
+// papers first.
+func (s *stratum) allocate(sampler Sampler, opts sampleOptions, paperPartitions []*subsets.Bitset) {
+	sort.Slice(s.papers, func(i, j int) bool {
+		if s.papers[i].rank != s.papers[j].rank {
+			return s.papers[i].rank < s.papers[j].rank
+		}
+		return s.papers[i].id < s.papers[j].id
+	})
+
+	start := 0
+	for i, fraction := range opts.Partitions {
+		n := int(math.Round(fraction * float64(len(s.papers))))
+		if opts.Quotas != nil {
+			n = opts.Quotas[i]
+		}
+
+		if sampler.Nested() {
+			// Nested partitions all start from the lowest ranked paper.
+			start = 0
+		}
+		end := min(start+n, len(s.papers))
+
+		for _, paper := range s.papers[start:end] {
+			paperPartitions[i].Add(paper.id)
+		}
+		start = end
+	}
+}
+
+func getPartitions(ctx context.Context, sampler Sampler, inPapers string, opts sampleOptions) ([]*subsets.Bitset, []*stratum, error) {
+	paperPartitions := make([]*subsets.Bitset, len(opts.Partitions))
+	for i := range paperPartitions {
+		paperPartitions[i] = &subsets.Bitset{}
+	}
+
+	strata := make(map[string]*stratum)
+	values := make([]string, len(opts.StratifyBy))
+	var sampled []int
+
+	columnNames := append([]string{tables.PaperIdFieldName, tables.SoftciteIdFieldName, tables.HasMentionsFieldName}, opts.StratifyBy...)
+	err := subsets.Scan(ctx, inPapers, columnNames, func(record arrow.Record) error {
+		idColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		softciteIdColumn, err := subsets.Column[*array.String](record, tables.SoftciteIdFieldName)
+		if err != nil {
+			return err
+		}
+		hasMentionsColumn, err := subsets.Column[*array.Boolean](record, tables.HasMentionsFieldName)
+		if err != nil {
+			return err
+		}
+		stratifyColumns := make([]arrow.Array, len(opts.StratifyBy))
+		for i, name := range opts.StratifyBy {
+			stratifyColumns[i], err = subsets.Column[arrow.Array](record, name)
+			if err != nil {
+				return err
+			}
 		}
 
 		for i, id := range idColumn.Uint32Values() {
-			partitionNum := -1
-			for i, partition := range partitions {
-				if _, found := partition[id]; found {
-					partitionNum = i
-					break
+			eligible := opts.IncludeWithoutMentions || hasMentionsColumn.Value(i)
+
+			softciteId := softciteIdColumn.Value(i)
+			if len(stratifyColumns) == 0 {
+				if !eligible {
+					continue
+				}
+				sampled = sampler.Sample(softciteId, sampled[:0])
+				for _, j := range sampled {
+					paperPartitions[j].Add(id)
 				}
+				continue
 			}
 
-			if partitionNum == -1 {
-				continue
+			for j, column := range stratifyColumns {
+				values[j] = column.ValueStr(i)
 			}
+			key := strings.Join(values, "\x1f")
 
-			recordBuilder := recordBuilders[partitionNum]
-
-			for j, field := range recordBuilder.Schema().Fields() {
-				switch field.Type {
-				case arrow.FixedWidthTypes.Boolean:
-					recordBuilder.Field(j).(*array.BooleanBuilder).Append(record.Column(j).(*array.Boolean).Value(i))
-				case arrow.PrimitiveTypes.Uint16:
-					recordBuilder.Field(j).(*array.Uint16Builder).Append(record.Column(j).(*array.Uint16).Value(i))
-				case arrow.PrimitiveTypes.Uint32:
-					recordBuilder.Field(j).(*array.Uint32Builder).Append(record.Column(j).(*array.Uint32).Value(i))
-				case arrow.PrimitiveTypes.Float64:
-					recordBuilder.Field(j).(*array.Float64Builder).Append(record.Column(j).(*array.Float64).Value(i))
-				case arrow.PrimitiveTypes.Date32:
-					recordBuilder.Field(j).(*array.Date32Builder).Append(record.Column(j).(*array.Date32).Value(i))
-				case arrow.BinaryTypes.String:
-					recordBuilder.Field(j).(*array.StringBuilder).Append(record.Column(j).(*array.String).Value(i))
-				default:
-					return fmt.Errorf("unsupported field type %s", field.Type)
-				}
+			s, found := strata[key]
+			if !found {
+				s = &stratum{values: slices.Clone(values)}
+				strata[key] = s
 			}
+			// Count every paper in the population so the sampling report
+			// compares partitions to the full dataset.
+			s.population++
+			if !eligible {
+				continue
+			}
+			s.papers = append(s.papers, rankedPaper{id: id, rank: sampler.Rank(softciteId)})
 		}
-	}
 
-	if !errors.Is(err, io.EOF) {
-		return fmt.Errorf("reading records: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading papers: %w", err)
 	}
 
-	for i := range partitions {
-		recordBuilder := recordBuilders[i]
-		writer := writers[i]
+	keys := make([]string, 0, len(strata))
+	for key := range strata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-		recordToWrite := recordBuilder.NewRecord()
-		defer recordToWrite.Release()
-		err = writer.Write(recordToWrite)
-		if err != nil {
-			return fmt.Errorf("writing record: %w", err)
-		}
+	result := make([]*stratum, len(keys))
+	for i, key := range keys {
+		result[i] = strata[key]
+		result[i].allocate(sampler, opts, paperPartitions)
 	}
 
-	return nil
+	return paperPartitions, result, nil
 }
 
-func getPartitions(ctx context.Context, seed int64, inPapers string, thresholds []float64) ([]map[uint32]struct{}, error) {
-	allocator := memory.NewGoAllocator()
-	inPapersFileReader, err := file.OpenParquetFile(inPapers, true)
-	if err != nil {
-		return nil, fmt.Errorf("opening parquet file %q: %w", inPapers, err)
+// writeSamplingReport writes, for each stratify-by column, the number and
+// share of papers with each value in the population of all papers and in each
+// partition.
+func writeSamplingReport(path string, stratifyBy []string, strata []*stratum, paperPartitions []*subsets.Bitset) error {
+	type marginal struct {
+		value      string
+		population int
+		samples    []int
 	}
 
-	inPapersReader, err := pqarrow.NewFileReader(inPapersFileReader,
-		pqarrow.ArrowReadProperties{Parallel: true, BatchSize: batchSize},
-		allocator,
-	)
+	reportFile, err := os.Create(path)
 	if err != nil {
-		return nil, fmt.Errorf("creating pqarrow FileReader: %w", err)
+		return fmt.Errorf("creating sampling report %q: %w", path, err)
 	}
+	defer func() {
+		err := reportFile.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
 
-	schema, err := inPapersReader.Schema()
+	writer := csv.NewWriter(reportFile)
+	err = writer.Write([]string{"partition", "column", "value", "population", "population_share", "sample", "sample_share"})
 	if err != nil {
-		return nil, fmt.Errorf("getting schema: %w", err)
-	}
-	var paperIdFieldIndex int
-	var hasMentionsFieldIndex int
-	for i, field := range schema.Fields() {
-		switch field.Name {
-		case tables.PaperIdFieldName:
-			paperIdFieldIndex = i
-		case tables.HasMentionsFieldName:
-			hasMentionsFieldIndex = i
-		}
+		return fmt.Errorf("writing sampling report: %w", err)
 	}
 
-	recordReader, err := inPapersReader.GetRecordReader(ctx, []int{paperIdFieldIndex, hasMentionsFieldIndex}, nil)
-	if err != nil {
-		return nil, fmt.Errorf("getting record reader: %w", err)
+	population := 0
+	sampleSizes := make([]int, len(paperPartitions))
+	for i, partition := range paperPartitions {
+		sampleSizes[i] = partition.Len()
+	}
+	for _, s := range strata {
+		population += s.population
 	}
-	rng := rand.New(rand.NewSource(seed))
-	paperPartitions := make([]map[uint32]struct{}, len(thresholds))
 
-	var record arrow.Record
-	for record, err = recordReader.Read(); err == nil; record, err = recordReader.Read() {
-		columns := record.Columns()
-		idColumn, ok := columns[0].(*array.Uint32)
-		if !ok {
-			return nil, fmt.Errorf("expected paper id column to be of type *array.Uint32, got %T", columns[0])
-		}
-		hasMentionsColumn, ok := columns[1].(*array.Boolean)
-		if !ok {
-			return nil, fmt.Errorf("expected has mentions column to be of type *array.Boolean, got %T", columns[1])
-		}
+	for j, column := range stratifyBy {
+		marginals := make(map[string]*marginal)
+		for _, s := range strata {
+			m, found := marginals[s.values[j]]
+			if !found {
+				m = &marginal{value: s.values[j], samples: make([]int, len(paperPartitions))}
+				marginals[s.values[j]] = m
+			}
 
-		for i, id := range idColumn.Uint32Values() {
-			if hasMentionsColumn.Value(i) {
-				randValue := rng.Float64()
-				for j, threshold := range thresholds {
-					if randValue < threshold {
-						if paperPartitions[j] == nil {
-							paperPartitions[j] = make(map[uint32]struct{})
-						}
-						paperPartitions[j][id] = struct{}{}
-						break
+			m.population += s.population
+			for _, paper := range s.papers {
+				for i, partition := range paperPartitions {
+					if partition.Contains(paper.id) {
+						m.samples[i]++
 					}
 				}
 			}
 		}
+
+		sorted := make([]*marginal, 0, len(marginals))
+		for _, m := range marginals {
+			sorted = append(sorted, m)
+		}
+		sort.Slice(sorted, func(a, b int) bool {
+			if sorted[a].population != sorted[b].population {
+				return sorted[a].population > sorted[b].population
+			}
+			return sorted[a].value < sorted[b].value
+		})
+
+		for i := range paperPartitions {
+			for _, m := range sorted {
+				err = writer.Write([]string{
+					strconv.Itoa(i),
+					column,
+					m.value,
+					strconv.Itoa(m.population),
+					strconv.FormatFloat(share(m.population, population), 'f', 6, 64),
+					strconv.Itoa(m.samples[i]),
+					strconv.FormatFloat(share(m.samples[i], sampleSizes[i]), 'f', 6, 64),
+				})
+				if err != nil {
+					return fmt.Errorf("writing sampling report: %w", err)
+				}
+			}
+		}
 	}
-	if !errors.Is(err, io.EOF) {
-		return nil, fmt.Errorf("reading records: %w", err)
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
 	}
-	return paperPartitions, nil
+	return float64(n) / float64(total)
 }
 
 func getSeed(cmd *cobra.Command) (int64, error) {
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func softciteIds(n int) []string {
+	result := make([]string, n)
+	for i := range result {
+		result[i] = fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+	}
+	return result
+}
+
+func TestHashSampler(t *testing.T) {
+	// Partitions need not be in order of fraction.
+	partitions := []float64{0.1, 0.01, 0.05}
+	sampler := newHashSampler("softcite", partitions)
+	ids := softciteIds(20000)
+
+	counts := make([]int, len(partitions))
+	for _, id := range ids {
+		sampled := sampler.Sample(id, nil)
+		for _, i := range sampled {
+			counts[i]++
+		}
+
+		// Each partition includes the papers of the smaller ones.
+		for i, partition := range partitions {
+			for j, other := range partitions {
+				if other < partition && slices.Contains(sampled, j) && !slices.Contains(sampled, i) {
+					t.Fatalf("%s is in partition %g but not %g", id, other, partition)
+				}
+			}
+		}
+
+		// Samples are the same for every sampler with the same salt.
+		if again := newHashSampler("softcite", partitions).Sample(id, nil); !slices.Equal(sampled, again) {
+			t.Fatalf("got %v then %v for %s", sampled, again, id)
+		}
+	}
+
+	for i, partition := range partitions {
+		got := float64(counts[i]) / float64(len(ids))
+		if got < 0.8*partition || got > 1.2*partition {
+			t.Errorf("partition %g has %g of papers", partition, got)
+		}
+	}
+
+	// A different salt samples different papers.
+	other := newHashSampler("other", partitions)
+	differ := 0
+	for _, id := range ids {
+		if sampler.Rank(id) != other.Rank(id) {
+			differ++
+		}
+	}
+	if differ < len(ids)*99/100 {
+		t.Errorf("%d of %d ranks differ between salts", differ, len(ids))
+	}
+}
+
+func TestHashUnit(t *testing.T) {
+	for _, id := range softciteIds(1000) {
+		got := hashUnit([]byte("softcite"), id)
+		if got < 0 || got >= 1 {
+			t.Fatalf("got %g for %s, want a value in [0, 1)", got, id)
+		}
+	}
+}
+
+func TestRandomSampler(t *testing.T) {
+	partitions := []float64{0.1, 0.2}
+	sampler := newRandomSampler(1, partitions)
+	ids := softciteIds(20000)
+
+	counts := make([]int, len(partitions))
+	for _, id := range ids {
+		sampled := sampler.Sample(id, nil)
+		// Partitions are disjoint.
+		if len(sampled) > 1 {
+			t.Fatalf("%s is in partitions %v", id, sampled)
+		}
+		for _, i := range sampled {
+			counts[i]++
+		}
+	}
+
+	for i, partition := range partitions {
+		got := float64(counts[i]) / float64(len(ids))
+		if got < 0.8*partition || got > 1.2*partition {
+			t.Errorf("partition %g has %g of papers", partition, got)
+		}
+	}
+}
+
+func TestCheckNestedQuotas(t *testing.T) {
+	tcs := []struct {
+		name       string
+		partitions []float64
+		quotas     []int
+		wantErr    bool
+	}{
+		{name: "increasing", partitions: []float64{0.01, 0.05}, quotas: []int{10, 50}},
+		{name: "equal", partitions: []float64{0.01, 0.05}, quotas: []int{10, 10}},
+		{name: "decreasing", partitions: []float64{0.01, 0.05}, quotas: []int{50, 10}, wantErr: true},
+		{name: "unordered partitions", partitions: []float64{0.05, 0.01}, quotas: []int{50, 10}},
+		{name: "unordered decreasing", partitions: []float64{0.05, 0.01, 0.1}, quotas: []int{50, 10, 20}, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNestedQuotas(tc.partitions, tc.quotas)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// rankSampler ranks papers by their softcite_id, as a number.
+type rankSampler struct {
+	nested bool
+}
+
+func (s rankSampler) Sample(string, []int) []int {
+	panic("stratified sampling only ranks papers")
+}
+
+func (s rankSampler) Rank(softciteId string) float64 {
+	var rank float64
+	_, _ = fmt.Sscan(softciteId, &rank)
+	return rank
+}
+
+func (s rankSampler) Nested() bool {
+	return s.nested
+}
+
+func TestStratum_Allocate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		sampler Sampler
+		opts    sampleOptions
+		want    [][]uint32
+	}{
+		{
+			// Each partition is its fraction of the stratum, rounded.
+			name:    "proportional nested",
+			sampler: rankSampler{nested: true},
+			opts:    sampleOptions{Partitions: []float64{0.2, 0.5}},
+			want:    [][]uint32{{8, 9}, {5, 6, 7, 8, 9}},
+		},
+		{
+			// Disjoint partitions take the next lowest ranked papers.
+			name:    "proportional disjoint",
+			sampler: rankSampler{nested: false},
+			opts:    sampleOptions{Partitions: []float64{0.2, 0.5}},
+			want:    [][]uint32{{8, 9}, {3, 4, 5, 6, 7}},
+		},
+		{
+			name:    "quotas",
+			sampler: rankSampler{nested: true},
+			opts:    sampleOptions{Partitions: []float64{0.01, 0.05}, Quotas: []int{1, 3}},
+			want:    [][]uint32{{9}, {7, 8, 9}},
+		},
+		{
+			// Quotas larger than the stratum take all of it.
+			name:    "quotas past the stratum",
+			sampler: rankSampler{nested: false},
+			opts:    sampleOptions{Partitions: []float64{0.01, 0.05}, Quotas: []int{8, 8}},
+			want:    [][]uint32{{2, 3, 4, 5, 6, 7, 8, 9}, {0, 1}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			// Paper i has rank 10 - i, so the highest ids are taken first.
+			s := &stratum{}
+			for i := range uint32(10) {
+				s.papers = append(s.papers, rankedPaper{id: i, rank: tc.sampler.Rank(fmt.Sprint(10 - i))})
+			}
+
+			paperPartitions := make([]*subsets.Bitset, len(tc.opts.Partitions))
+			for i := range paperPartitions {
+				paperPartitions[i] = &subsets.Bitset{}
+			}
+			s.allocate(tc.sampler, tc.opts, paperPartitions)
+
+			if diff := cmp.Diff(tc.want, members(paperPartitions, 10)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// members returns the ids below n in each partition, in order.
+func members(paperPartitions []*subsets.Bitset, n uint32) [][]uint32 {
+	result := make([][]uint32, len(paperPartitions))
+	for i, partition := range paperPartitions {
+		for id := range n {
+			if partition.Contains(id) {
+				result[i] = append(result[i], id)
+			}
+		}
+	}
+	return result
+}
+
+// writePapers writes a papers table with the columns getPartitions reads.
+func writePapers(t *testing.T, years []uint16, hasMentions []bool) string {
+	t.Helper()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: tables.PaperIdFieldName, Type: arrow.PrimitiveTypes.Uint32},
+		{Name: tables.SoftciteIdFieldName, Type: arrow.BinaryTypes.String},
+		{Name: tables.PublishedYearFieldName, Type: arrow.PrimitiveTypes.Uint16},
+		{Name: tables.HasMentionsFieldName, Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer recordBuilder.Release()
+	for i, year := range years {
+		recordBuilder.Field(0).(*array.Uint32Builder).Append(uint32(i))
+		recordBuilder.Field(1).(*array.StringBuilder).Append(fmt.Sprint(i))
+		recordBuilder.Field(2).(*array.Uint16Builder).Append(year)
+		recordBuilder.Field(3).(*array.BooleanBuilder).Append(hasMentions[i])
+	}
+
+	path := filepath.Join(t.TempDir(), tables.PapersName+tables.ParquetExt)
+	err := subsets.WriteRecord(path, schema, recordBuilder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGetPartitions_Stratified(t *testing.T) {
+	// Papers 0-5 are from 2019 and papers 6-9 from 2020. Papers 4, 5, and 9
+	// have no mentions.
+	years := []uint16{2019, 2019, 2019, 2019, 2019, 2019, 2020, 2020, 2020, 2020}
+	hasMentions := []bool{true, true, true, true, false, false, true, true, true, false}
+	inPapers := writePapers(t, years, hasMentions)
+
+	opts := sampleOptions{
+		Partitions: []float64{0.5},
+		StratifyBy: []string{tables.PublishedYearFieldName},
+	}
+	paperPartitions, strata, err := getPartitions(context.Background(), rankSampler{nested: true}, inPapers, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each year is sampled separately, from the lowest ranked papers with
+	// mentions, and counts its papers without mentions in its population.
+	gotStrata := make(map[string][2]int)
+	for _, s := range strata {
+		gotStrata[s.values[0]] = [2]int{s.population, len(s.papers)}
+	}
+	wantStrata := map[string][2]int{"2019": {6, 4}, "2020": {4, 3}}
+	if diff := cmp.Diff(wantStrata, gotStrata); diff != "" {
+		t.Error(diff)
+	}
+
+	// Half of 2019's four papers with mentions, and half of 2020's three,
+	// rounded.
+	want := [][]uint32{{0, 1, 6, 7}}
+	if diff := cmp.Diff(want, members(paperPartitions, 10)); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/software-mentions/pkg/subsets"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+const (
+	FlagSource         = "source"
+	FlagSoftwareColumn = "software-column"
+	FlagSoftware       = "software"
+	FlagMinPapers      = "min-papers"
+	FlagScope          = "scope"
+	FlagMinCertainty   = "min-certainty"
+)
+
+func init() {
+	cmd.Flags().String(FlagSource, "pdf", "source file type of the mentions and purpose_assessments tables")
+	cmd.Flags().String(FlagSoftwareColumn, tables.SoftwareCanonicalFieldName, "mentions column identifying the software")
+	cmd.Flags().StringSlice(FlagSoftware, nil, "only count these software (default: all)")
+	cmd.Flags().Int(FlagMinPapers, 1, "minimum number of papers over all years mentioning a software for it to be counted")
+	cmd.Flags().String(FlagScope, "document", "scope of the purpose assessments of used mentions, one of [document|local]")
+	cmd.Flags().Float64(FlagMinCertainty, 0.5, "certainty_score the purpose assessments of used mentions must be above")
+}
+
+func main() {
+	err := cmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+var cmd = cobra.Command{
+	Use:   "trends IN_DIR OUT_FILE",
+	Short: "Count the papers mentioning each software per year",
+	Long: `Count the papers mentioning each software per year.
+
+For each software and published year, counts the papers mentioning the software,
+its mentions, and the papers with a mention assessed as used at --scope with a
+certainty score above --min-certainty. Paper counts are also divided by the number of papers
+published that year. Papers without a published year are not counted.
+
+The format is chosen by the extension of OUT_FILE, .parquet or .csv.`,
+	Args:    cobra.ExactArgs(2),
+	Version: "0.1.0",
+	RunE:    runE,
+}
+
+var ErrTrends = errors.New("counting software trends")
+
+const csvExt = ".csv"
+
+type softwareYear struct {
+	software uint32
+	year     uint16
+}
+
+type softwarePaper struct {
+	software uint32
+	paper    uint32
+}
+
+type trend struct {
+	papers     uint32
+	mentions   uint32
+	usedPapers uint32
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inDir := args[0]
+	outPath := args[1]
+	switch ext := filepath.Ext(outPath); ext {
+	case tables.ParquetExt, csvExt:
+	default:
+		return fmt.Errorf("%w: unknown output file extension %q, want %s or %s", ErrTrends, ext, tables.ParquetExt, csvExt)
+	}
+
+	source, err := cmd.Flags().GetString(FlagSource)
+	if err != nil {
+		return err
+	}
+	softwareColumn, err := cmd.Flags().GetString(FlagSoftwareColumn)
+	if err != nil {
+		return err
+	}
+	softwareList, err := cmd.Flags().GetStringSlice(FlagSoftware)
+	if err != nil {
+		return err
+	}
+	minPapers, err := cmd.Flags().GetInt(FlagMinPapers)
+	if err != nil {
+		return err
+	}
+	scope, err := cmd.Flags().GetString(FlagScope)
+	if err != nil {
+		return err
+	}
+	minCertainty, err := cmd.Flags().GetFloat64(FlagMinCertainty)
+	if err != nil {
+		return err
+	}
+
+	var onlySoftware map[string]bool
+	if len(softwareList) > 0 {
+		onlySoftware = make(map[string]bool, len(softwareList))
+		for _, software := range softwareList {
+			onlySoftware[software] = true
+		}
+	}
+
+	purposes, err := subsets.OpenPurposes(inDir, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTrends, err)
+	}
+	usedMentions, err := purposes.MatchingMentions(ctx, scope, []string{"used"}, minCertainty)
+	if err != nil {
+		return fmt.Errorf("%w: reading purpose assessments: %w", ErrTrends, err)
+	}
+
+	papersPath := filepath.Join(inDir, tables.PapersName+tables.ParquetExt)
+
+	years := make(map[uint32]uint16)
+	totalPapers := make(map[uint16]uint32)
+	columns := []string{tables.PaperIdFieldName, tables.PublishedYearFieldName}
+	err = subsets.Scan(ctx, papersPath, columns, func(record arrow.Record) error {
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		yearColumn, err := subsets.Column[*array.Uint16](record, tables.PublishedYearFieldName)
+		if err != nil {
+			return err
+		}
+
+		for i, paperId := range paperIdColumn.Uint32Values() {
+			if yearColumn.IsNull(i) {
+				continue
+			}
+			year := yearColumn.Value(i)
+			years[paperId] = year
+			totalPapers[year]++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading papers: %w", ErrTrends, err)
+	}
+
+	counter := newTrendCounter(years, usedMentions, onlySoftware)
+	columns = []string{tables.SoftwareMentionIdFieldName, tables.PaperIdFieldName, softwareColumn}
+	err = subsets.Scan(ctx, purposes.MentionsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := subsets.Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		paperIdColumn, err := subsets.Column[*array.Uint32](record, tables.PaperIdFieldName)
+		if err != nil {
+			return err
+		}
+		softwareColumn, err := subsets.Column[*array.String](record, softwareColumn)
+		if err != nil {
+			return err
+		}
+
+		for i, paperId := range paperIdColumn.Uint32Values() {
+			if softwareColumn.IsNull(i) {
+				continue
+			}
+			counter.add(mentionIdColumn.Value(i), paperId, softwareColumn.Value(i))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reading mentions: %w", ErrTrends, err)
+	}
+
+	softwareNames := counter.softwareNames
+	keys, trends := counter.trends(minPapers)
+
+	if filepath.Ext(outPath) == csvExt {
+		err = writeCsv(outPath, softwareNames, keys, trends, totalPapers)
+	} else {
+		err = writeParquet(outPath, softwareNames, keys, trends, totalPapers)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: writing %q: %w", ErrTrends, outPath, err)
+	}
+
+	return nil
+}
+
+// trendCounter counts the papers and mentions of each software per year.
+type trendCounter struct {
+	// years is the published year of each paper, by paper id.
+	years        map[uint32]uint16
+	usedMentions map[string]struct{}
+	// onlySoftware, if not nil, is the software to count.
+	onlySoftware map[string]bool
+
+	// Software are numbered in order of first mention.
+	softwareNames []string
+	softwareIds   map[string]uint32
+	nMentions     map[softwareYear]uint32
+	// Whether each paper mentioning a software has a used mention of it.
+	used map[softwarePaper]bool
+}
+
+func newTrendCounter(years map[uint32]uint16, usedMentions map[string]struct{}, onlySoftware map[string]bool) *trendCounter {
+	return &trendCounter{
+		years:        years,
+		usedMentions: usedMentions,
+		onlySoftware: onlySoftware,
+		softwareIds:  make(map[string]uint32),
+		nMentions:    make(map[softwareYear]uint32),
+		used:         make(map[softwarePaper]bool),
+	}
+}
+
+// add counts a mention of software in paperId. Mentions in papers without a
+// published year are not counted.
+func (c *trendCounter) add(mentionId string, paperId uint32, software string) {
+	year, found := c.years[paperId]
+	if !found {
+		return
+	}
+	if software == "" || c.onlySoftware != nil && !c.onlySoftware[software] {
+		return
+	}
+
+	softwareId, found := c.softwareIds[software]
+	if !found {
+		softwareId = uint32(len(c.softwareNames))
+		c.softwareIds[software] = softwareId
+		c.softwareNames = append(c.softwareNames, software)
+	}
+
+	c.nMentions[softwareYear{software: softwareId, year: year}]++
+	_, isUsed := c.usedMentions[mentionId]
+	key := softwarePaper{software: softwareId, paper: paperId}
+	c.used[key] = c.used[key] || isUsed
+}
+
+// trends returns the counts of each software and year, for software mentioned
+// in at least minPapers papers over all years, with their keys sorted by
+// software name and then year.
+func (c *trendCounter) trends(minPapers int) ([]softwareYear, map[softwareYear]*trend) {
+	trends := make(map[softwareYear]*trend, len(c.nMentions))
+	for key, count := range c.nMentions {
+		trends[key] = &trend{mentions: count}
+	}
+	nPapers := make([]int, len(c.softwareNames))
+	for key, isUsed := range c.used {
+		t := trends[softwareYear{software: key.software, year: c.years[key.paper]}]
+		t.papers++
+		if isUsed {
+			t.usedPapers++
+		}
+		nPapers[key.software]++
+	}
+
+	keys := make([]softwareYear, 0, len(trends))
+	for key := range trends {
+		if nPapers[key.software] >= minPapers {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].software != keys[j].software {
+			return c.softwareNames[keys[i].software] < c.softwareNames[keys[j].software]
+		}
+		return keys[i].year < keys[j].year
+	})
+
+	return keys, trends
+}
+
+func share(papers, total uint32) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(papers) / float64(total)
+}
+
+func writeParquet(outPath string, softwareNames []string, keys []softwareYear, trends map[softwareYear]*trend, totalPapers map[uint16]uint32) error {
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), tables.SoftwareTrends)
+	defer recordBuilder.Release()
+
+	fields := recordBuilder.Fields()
+	softwareField := fields[0].(*array.StringBuilder)
+	yearField := fields[1].(*array.Uint16Builder)
+	papersField := fields[2].(*array.Uint32Builder)
+	mentionsField := fields[3].(*array.Uint32Builder)
+	usedPapersField := fields[4].(*array.Uint32Builder)
+	totalPapersField := fields[5].(*array.Uint32Builder)
+	papersShareField := fields[6].(*array.Float64Builder)
+	usedPapersShareField := fields[7].(*array.Float64Builder)
+
+	for _, key := range keys {
+		t := trends[key]
+		total := totalPapers[key.year]
+
+		softwareField.Append(softwareNames[key.software])
+		yearField.Append(key.year)
+		papersField.Append(t.papers)
+		mentionsField.Append(t.mentions)
+		usedPapersField.Append(t.usedPapers)
+		totalPapersField.Append(total)
+		papersShareField.Append(share(t.papers, total))
+		usedPapersShareField.Append(share(t.usedPapers, total))
+	}
+
+	return subsets.WriteRecord(outPath, tables.SoftwareTrends, recordBuilder)
+}
+
+func writeCsv(outPath string, softwareNames []string, keys []softwareYear, trends map[softwareYear]*trend, totalPapers map[uint16]uint32) error {
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := outFile.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	writer := csv.NewWriter(outFile)
+
+	header := make([]string, len(tables.SoftwareTrends.Fields()))
+	for i, field := range tables.SoftwareTrends.Fields() {
+		header[i] = field.Name
+	}
+	err = writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		t := trends[key]
+		total := totalPapers[key.year]
+
+		err = writer.Write([]string{
+			softwareNames[key.software],
+			strconv.Itoa(int(key.year)),
+			strconv.Itoa(int(t.papers)),
+			strconv.Itoa(int(t.mentions)),
+			strconv.Itoa(int(t.usedPapers)),
+			strconv.Itoa(int(total)),
+			strconv.FormatFloat(share(t.papers, total), 'g', -1, 64),
+			strconv.FormatFloat(share(t.usedPapers, total), 'g', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+type mention struct {
+	id       string
+	paper    uint32
+	software string
+}
+
+// row is a trend with its software and year, for comparison.
+type row struct {
+	software   string
+	year       uint16
+	papers     uint32
+	mentions   uint32
+	usedPapers uint32
+}
+
+func countTrends(years map[uint32]uint16, used []string, onlySoftware map[string]bool, minPapers int, mentions []mention) []row {
+	usedMentions := make(map[string]struct{}, len(used))
+	for _, id := range used {
+		usedMentions[id] = struct{}{}
+	}
+
+	counter := newTrendCounter(years, usedMentions, onlySoftware)
+	for _, m := range mentions {
+		counter.add(m.id, m.paper, m.software)
+	}
+
+	keys, trends := counter.trends(minPapers)
+	var result []row
+	for _, key := range keys {
+		t := trends[key]
+		result = append(result, row{
+			software:   counter.softwareNames[key.software],
+			year:       key.year,
+			papers:     t.papers,
+			mentions:   t.mentions,
+			usedPapers: t.usedPapers,
+		})
+	}
+	return result
+}
+
+func TestTrendCounter(t *testing.T) {
+	// Paper 4 has no published year.
+	years := map[uint32]uint16{1: 2019, 2: 2019, 3: 2020}
+
+	tcs := []struct {
+		name         string
+		used         []string
+		onlySoftware map[string]bool
+		minPapers    int
+		mentions     []mention
+		want         []row
+	}{
+		{
+			name: "per year",
+			mentions: []mention{
+				{id: "1.0", paper: 1, software: "R"},
+				{id: "1.1", paper: 1, software: "R"},
+				{id: "2.0", paper: 2, software: "R"},
+				{id: "3.0", paper: 3, software: "R"},
+				{id: "3.1", paper: 3, software: "SPSS"},
+				{id: "4.0", paper: 4, software: "R"},
+				{id: "2.1", paper: 2, software: ""},
+			},
+			want: []row{
+				{software: "R", year: 2019, papers: 2, mentions: 3},
+				{software: "R", year: 2020, papers: 1, mentions: 1},
+				{software: "SPSS", year: 2020, papers: 1, mentions: 1},
+			},
+		},
+		{
+			// A paper is used if any of its mentions of the software is.
+			name: "used papers",
+			used: []string{"1.1", "3.1"},
+			mentions: []mention{
+				{id: "1.0", paper: 1, software: "R"},
+				{id: "1.1", paper: 1, software: "R"},
+				{id: "2.0", paper: 2, software: "R"},
+				{id: "3.0", paper: 3, software: "R"},
+				{id: "3.1", paper: 3, software: "SPSS"},
+			},
+			want: []row{
+				{software: "R", year: 2019, papers: 2, mentions: 3, usedPapers: 1},
+				{software: "R", year: 2020, papers: 1, mentions: 1},
+				{software: "SPSS", year: 2020, papers: 1, mentions: 1, usedPapers: 1},
+			},
+		},
+		{
+			// Papers are counted over all years, and mentions do not count.
+			name:      "min papers",
+			minPapers: 2,
+			mentions: []mention{
+				{id: "1.0", paper: 1, software: "R"},
+				{id: "3.0", paper: 3, software: "R"},
+				{id: "1.1", paper: 1, software: "SPSS"},
+				{id: "1.2", paper: 1, software: "SPSS"},
+				{id: "1.3", paper: 1, software: "SPSS"},
+			},
+			want: []row{
+				{software: "R", year: 2019, papers: 1, mentions: 1},
+				{software: "R", year: 2020, papers: 1, mentions: 1},
+			},
+		},
+		{
+			// Papers without a published year do not count toward --min-papers.
+			name:      "min papers without year",
+			minPapers: 2,
+			mentions: []mention{
+				{id: "1.0", paper: 1, software: "R"},
+				{id: "4.0", paper: 4, software: "R"},
+			},
+			want: nil,
+		},
+		{
+			name:         "only software",
+			onlySoftware: map[string]bool{"SPSS": true},
+			mentions: []mention{
+				{id: "1.0", paper: 1, software: "R"},
+				{id: "3.1", paper: 3, software: "SPSS"},
+			},
+			want: []row{
+				{software: "SPSS", year: 2020, papers: 1, mentions: 1},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := countTrends(years, tc.used, tc.onlySoftware, tc.minPapers, tc.mentions)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(row{})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package associations measures how strongly two kinds of items, such as two
+// software, are associated by how often they occur together.
+package associations
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrMeasure = errors.New("unknown association measure")
+
+// Counts are the numbers of units, such as papers, with each item.
+type Counts struct {
+	// Total is the number of units.
+	Total int
+	// A and B are the numbers of units with each item.
+	A, B int
+	// Both is the number of units with both items.
+	Both int
+}
+
+// Measures are association measures computed from Counts. Measures undefined
+// for the counts, such as PMI when the items never co-occur, are NaN.
+type Measures struct {
+	// PMI is the pointwise mutual information, log(P(a,b) / (P(a) P(b))).
+	PMI float64
+	// NPMI is PMI normalized by -log(P(a,b)) to [-1, 1].
+	NPMI float64
+	// Jaccard is the number of units with both items over those with either.
+	Jaccard float64
+	// Lift is P(a,b) / (P(a) P(b)).
+	Lift float64
+	// ChiSquare is Pearson's chi-square statistic of the 2x2 contingency table.
+	ChiSquare float64
+	// PValue is the probability of a chi-square at least as large if the items
+	// were independent, with one degree of freedom. It underflows to 0 for
+	// chi-square statistics above about 1500, common for large numbers of units.
+	PValue float64
+	// LogPValue is the natural logarithm of PValue, which does not underflow.
+	LogPValue float64
+}
+
+// Compute computes the association measures of c.
+func Compute(c Counts) Measures {
+	nan := math.NaN()
+	result := Measures{PMI: nan, NPMI: nan, Jaccard: nan, Lift: nan, ChiSquare: nan, PValue: nan, LogPValue: nan}
+
+	total := float64(c.Total)
+	a, b, both := float64(c.A), float64(c.B), float64(c.Both)
+
+	if either := a + b - both; either > 0 {
+		result.Jaccard = both / either
+	}
+
+	if total == 0 || a == 0 || b == 0 {
+		return result
+	}
+
+	result.Lift = both * total / (a * b)
+	if both > 0 {
+		result.PMI = math.Log(result.Lift)
+		if both < total {
+			result.NPMI = result.PMI / -math.Log(both/total)
+		} else {
+			result.NPMI = 1
+		}
+	}
+
+	// The 2x2 contingency table of units with and without each item.
+	onlyA := a - both
+	onlyB := b - both
+	neither := total - a - b + both
+	denominator := a * (total - a) * b * (total - b)
+	if denominator > 0 {
+		d := both*neither - onlyA*onlyB
+		result.ChiSquare = total * d * d / denominator
+		result.PValue = math.Erfc(math.Sqrt(result.ChiSquare / 2))
+		result.LogPValue = logErfc(math.Sqrt(result.ChiSquare / 2))
+	}
+
+	return result
+}
+
+// logErfc returns log(erfc(z)). Above z = 20, where erfc(z) < 1e-175, it uses
+// the asymptotic expansion
+//
+//	erfc(z) ≈ exp(-z²) / (z √π) (1 - 1/(2z²) + 3/(4z⁴) - 15/(8z⁶) + 105/(16z⁸))
+//
+// since erfc(z) underflows to 0 above z ≈ 27.
+func logErfc(z float64) float64 {
+	if z < 20 {
+		return math.Log(math.Erfc(z))
+	}
+
+	z2 := z * z
+	series := 1 - 1/(2*z2) + 3/(4*z2*z2) - 15/(8*z2*z2*z2) + 105/(16*z2*z2*z2*z2)
+	return -z2 - math.Log(z*math.Sqrt(math.Pi)) + math.Log(series)
+}
+
+// Measure names one of the Measures, or the co-occurrence count.
+type Measure string
+
+const (
+	Count     Measure = "count"
+	PMI       Measure = "pmi"
+	NPMI      Measure = "npmi"
+	Jaccard   Measure = "jaccard"
+	Lift      Measure = "lift"
+	ChiSquare Measure = "chi_square"
+	PValue    Measure = "p_value"
+	LogPValue Measure = "log_p_value"
+)
+
+var AllMeasures = []Measure{Count, PMI, NPMI, Jaccard, Lift, ChiSquare, PValue, LogPValue}
+
+// ParseMeasure returns the Measure named s.
+func ParseMeasure(s string) (Measure, error) {
+	for _, measure := range AllMeasures {
+		if string(measure) == s {
+			return measure, nil
+		}
+	}
+
+	names := make([]string, len(AllMeasures))
+	for i, measure := range AllMeasures {
+		names[i] = string(measure)
+	}
+	return "", fmt.Errorf("%w: %q, want one of [%s]", ErrMeasure, s, strings.Join(names, "|"))
+}
+
+// Score returns the value of measure for c and m, oriented so that larger
+// scores are stronger associations. NaN scores as negative infinity.
+func (measure Measure) Score(c Counts, m Measures) float64 {
+	var result float64
+	switch measure {
+	case Count:
+		result = float64(c.Both)
+	case PMI:
+		result = m.PMI
+	case NPMI:
+		result = m.NPMI
+	case Jaccard:
+		result = m.Jaccard
+	case Lift:
+		result = m.Lift
+	case ChiSquare:
+		result = m.ChiSquare
+	case PValue, LogPValue:
+		// Rank by the logarithm so p-values which underflow to 0 still order.
+		result = -m.LogPValue
+	default:
+		panic("invalid measure " + measure)
+	}
+
+	if math.IsNaN(result) {
+		return math.Inf(-1)
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package associations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	// 100 units, 20 with a, 10 with b, 5 with both.
+	got := Compute(Counts{Total: 100, A: 20, B: 10, Both: 5})
+
+	want := Measures{
+		PMI:       math.Log(2.5),
+		NPMI:      math.Log(2.5) / math.Log(20),
+		Jaccard:   5.0 / 25.0,
+		Lift:      2.5,
+		ChiSquare: 100 * (5*75 - 15*5) * (5*75 - 15*5) / (20.0 * 80 * 10 * 90),
+	}
+	want.PValue = math.Erfc(math.Sqrt(want.ChiSquare / 2))
+	want.LogPValue = math.Log(want.PValue)
+
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"PMI", got.PMI, want.PMI},
+		{"NPMI", got.NPMI, want.NPMI},
+		{"Jaccard", got.Jaccard, want.Jaccard},
+		{"Lift", got.Lift, want.Lift},
+		{"ChiSquare", got.ChiSquare, want.ChiSquare},
+		{"PValue", got.PValue, want.PValue},
+		{"LogPValue", got.LogPValue, want.LogPValue},
+	} {
+		if math.Abs(tc.got-tc.want) > 1e-12 {
+			t.Errorf("got %s %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestCompute_Undefined(t *testing.T) {
+	got := Compute(Counts{Total: 100, A: 0, B: 10})
+	for name, x := range map[string]float64{
+		"PMI":       got.PMI,
+		"Lift":      got.Lift,
+		"ChiSquare": got.ChiSquare,
+		"PValue":    got.PValue,
+		"LogPValue": got.LogPValue,
+	} {
+		if !math.IsNaN(x) {
+			t.Errorf("got %s %v, want NaN", name, x)
+		}
+	}
+
+	got = Compute(Counts{Total: 100, A: 20, B: 10, Both: 0})
+	if !math.IsNaN(got.PMI) {
+		t.Errorf("got PMI %v for items which never co-occur, want NaN", got.PMI)
+	}
+}
+
+func TestLogErfc(t *testing.T) {
+	// The asymptotic expansion agrees with erfc where erfc does not underflow.
+	for _, z := range []float64{20, 22, 25} {
+		want := math.Log(math.Erfc(z))
+		got := logErfc(z)
+		if math.Abs(got-want) > 1e-9*math.Abs(want) {
+			t.Errorf("got log(erfc(%v)) %v, want %v", z, got, want)
+		}
+	}
+
+	if got := logErfc(40); math.IsInf(got, 0) || got > -1600 {
+		t.Errorf("got log(erfc(40)) %v, want a finite value below -1600", got)
+	}
+}
+
+func TestScore_PValueUnderflow(t *testing.T) {
+	// Both associations are strong enough that their p-values underflow to 0.
+	weaker := Counts{Total: 10_000_000, A: 10_000, B: 10_000, Both: 1_000}
+	stronger := Counts{Total: 10_000_000, A: 10_000, B: 10_000, Both: 5_000}
+
+	weakerMeasures, strongerMeasures := Compute(weaker), Compute(stronger)
+	if weakerMeasures.PValue != 0 || strongerMeasures.PValue != 0 {
+		t.Fatalf("got p-values %v and %v, want both to underflow to 0", weakerMeasures.PValue, strongerMeasures.PValue)
+	}
+
+	for _, measure := range []Measure{PValue, LogPValue} {
+		if measure.Score(weaker, weakerMeasures) >= measure.Score(stronger, strongerMeasures) {
+			t.Errorf("got %s of the stronger association no greater than the weaker", measure)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package calibration measures how well certainty scores predict hand-labelled
+// purposes, and recommends score thresholds for deciding a purpose.
+package calibration
+
+import (
+	"math"
+	"sort"
+)
+
+// Sample is a certainty score and whether the labelled purpose is true.
+type Sample struct {
+	Score float64
+	Label bool
+}
+
+// Bin is a bin of a reliability diagram: the samples with a score in
+// [Lower, Upper), or [Lower, Upper] for the last bin.
+type Bin struct {
+	Lower float64
+	Upper float64
+	N     int
+	// MeanScore is the mean score of the samples, NaN if there are none.
+	MeanScore float64
+	// PositiveRate is the fraction of the samples labelled true, NaN if there
+	// are none. A calibrated score has PositiveRate near MeanScore.
+	PositiveRate float64
+}
+
+// Reliability bins samples into nBins bins of equal width over [0, 1].
+func Reliability(samples []Sample, nBins int) []Bin {
+	bins := make([]Bin, nBins)
+	sums := make([]float64, nBins)
+	positives := make([]int, nBins)
+	for i := range bins {
+		bins[i].Lower = float64(i) / float64(nBins)
+		bins[i].Upper = float64(i+1) / float64(nBins)
+	}
+
+	for _, sample := range samples {
+		i := min(max(int(sample.Score*float64(nBins)), 0), nBins-1)
+		// Multiplying can round scores across an edge, like 0.57 of 100 bins
+		// into the bin below, so compare to the edges themselves.
+		if i < nBins-1 && sample.Score >= bins[i+1].Lower {
+			i++
+		} else if i > 0 && sample.Score < bins[i].Lower {
+			i--
+		}
+		bins[i].N++
+		sums[i] += sample.Score
+		if sample.Label {
+			positives[i]++
+		}
+	}
+
+	for i := range bins {
+		bins[i].MeanScore = sums[i] / float64(bins[i].N)
+		bins[i].PositiveRate = float64(positives[i]) / float64(bins[i].N)
+	}
+
+	return bins
+}
+
+// Brier is the mean squared difference between the scores and labels of
+// samples, from 0 for perfect predictions to 1. It is NaN without samples.
+func Brier(samples []Sample) float64 {
+	sum := 0.0
+	for _, sample := range samples {
+		label := 0.0
+		if sample.Label {
+			label = 1.0
+		}
+		sum += (sample.Score - label) * (sample.Score - label)
+	}
+	return sum / float64(len(samples))
+}
+
+// Point is the confusion matrix of deciding samples with a score of at least
+// Threshold are true.
+type Point struct {
+	Threshold      float64
+	TruePositives  int
+	FalsePositives int
+	FalseNegatives int
+	TrueNegatives  int
+	// Precision, Recall, and F1 are NaN when undefined, such as the precision
+	// of a threshold above every score.
+	Precision float64
+	Recall    float64
+	F1        float64
+}
+
+// Thresholds returns the thresholds from step to 1-step in increments of step.
+func Thresholds(step float64) []float64 {
+	var thresholds []float64
+	// Count in integers, and round off floating point error, so thresholds
+	// like 0.3 are exact rather than 0.30000000000000004.
+	for i := 1; float64(i)*step < 1-step/2; i++ {
+		thresholds = append(thresholds, math.Round(float64(i)*step*1e9)/1e9)
+	}
+	return thresholds
+}
+
+// Curve returns the Point of each threshold.
+func Curve(samples []Sample, thresholds []float64) []Point {
+	points := make([]Point, len(thresholds))
+	for i, threshold := range thresholds {
+		p := Point{Threshold: threshold}
+		for _, sample := range samples {
+			decided := sample.Score >= threshold
+			switch {
+			case decided && sample.Label:
+				p.TruePositives++
+			case decided:
+				p.FalsePositives++
+			case sample.Label:
+				p.FalseNegatives++
+			default:
+				p.TrueNegatives++
+			}
+		}
+
+		p.Precision = float64(p.TruePositives) / float64(p.TruePositives+p.FalsePositives)
+		p.Recall = float64(p.TruePositives) / float64(p.TruePositives+p.FalseNegatives)
+		p.F1 = 2 * p.Precision * p.Recall / (p.Precision + p.Recall)
+		if p.TruePositives == 0 && p.FalsePositives+p.FalseNegatives > 0 {
+			p.F1 = 0
+		}
+		points[i] = p
+	}
+	return points
+}
+
+// BestF1 returns the point with the largest F1, preferring the first among
+// ties, or false if no point has a defined F1.
+func BestF1(points []Point) (Point, bool) {
+	var best Point
+	found := false
+	for _, p := range points {
+		if math.IsNaN(p.F1) {
+			continue
+		}
+		if !found || p.F1 > best.F1 {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// MinPrecision returns the point with the lowest threshold whose precision is
+// at least minPrecision, or false if there is none.
+func MinPrecision(points []Point, minPrecision float64) (Point, bool) {
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Threshold < sorted[j].Threshold
+	})
+
+	for _, p := range sorted {
+		if p.Precision >= minPrecision {
+			return p, true
+		}
+	}
+	return Point{}, false
+}
@@ -0,0 +1,169 @@
+package calibration
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"math"
+	"testing"
+)
+
+func TestReliability(t *testing.T) {
+	samples := []Sample{
+		{Score: 0.0, Label: false},
+		{Score: 0.2, Label: false},
+		{Score: 0.25, Label: true},
+		{Score: 0.5, Label: true},
+		{Score: 0.75, Label: true},
+		{Score: 1.0, Label: true},
+		// Scores outside [0, 1] are in the first and last bins.
+		{Score: -0.5, Label: false},
+		{Score: 1.5, Label: true},
+	}
+
+	got := Reliability(samples, 4)
+
+	nan := math.NaN()
+	want := []Bin{
+		{Lower: 0, Upper: 0.25, N: 3, MeanScore: (0.0 + 0.2 - 0.5) / 3, PositiveRate: 0},
+		{Lower: 0.25, Upper: 0.5, N: 1, MeanScore: 0.25, PositiveRate: 1},
+		{Lower: 0.5, Upper: 0.75, N: 1, MeanScore: 0.5, PositiveRate: 1},
+		{Lower: 0.75, Upper: 1, N: 3, MeanScore: (0.75 + 1.0 + 1.5) / 3, PositiveRate: 1},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateNaNs(), cmpopts.EquateApprox(0, 1e-12)); diff != "" {
+		t.Error(diff)
+	}
+
+	got = Reliability(nil, 2)
+	want = []Bin{
+		{Lower: 0, Upper: 0.5, MeanScore: nan, PositiveRate: nan},
+		{Lower: 0.5, Upper: 1, MeanScore: nan, PositiveRate: nan},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateNaNs()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestReliability_BinEdges(t *testing.T) {
+	// Scores on a bin's lower edge are in that bin, even where multiplying by
+	// the number of bins rounds down, as 0.57*100 does, and scores just below
+	// an edge are in the bin below it.
+	for _, nBins := range []int{3, 7, 10, 100} {
+		for i := range nBins {
+			score := float64(i) / float64(nBins)
+			bins := Reliability([]Sample{{Score: score}}, nBins)
+			if bins[i].N != 1 {
+				t.Errorf("got score %v outside bin [%v, %v) of %d bins", score, bins[i].Lower, bins[i].Upper, nBins)
+			}
+
+			if i == 0 {
+				continue
+			}
+			below := math.Nextafter(score, 0)
+			bins = Reliability([]Sample{{Score: below}}, nBins)
+			if bins[i-1].N != 1 {
+				t.Errorf("got score %v outside bin [%v, %v) of %d bins", below, bins[i-1].Lower, bins[i-1].Upper, nBins)
+			}
+		}
+	}
+}
+
+func TestBrier(t *testing.T) {
+	got := Brier([]Sample{
+		{Score: 1, Label: true},
+		{Score: 0.5, Label: false},
+		{Score: 0, Label: true},
+		{Score: 0.25, Label: false},
+	})
+	want := (0 + 0.25 + 1 + 0.0625) / 4
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := Brier(nil); !math.IsNaN(got) {
+		t.Errorf("got %v without samples, want NaN", got)
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	want := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	if diff := cmp.Diff(want, Thresholds(0.1)); diff != "" {
+		t.Error(diff)
+	}
+	if got := len(Thresholds(0.05)); got != 19 {
+		t.Errorf("got %d thresholds for step 0.05, want 19", got)
+	}
+}
+
+func TestCurve(t *testing.T) {
+	samples := []Sample{
+		{Score: 0.9, Label: true},
+		{Score: 0.8, Label: false},
+		{Score: 0.6, Label: true},
+		{Score: 0.4, Label: true},
+		{Score: 0.2, Label: false},
+	}
+
+	nan := math.NaN()
+	got := Curve(samples, []float64{0.1, 0.5, 0.85, 0.95})
+	want := []Point{
+		{Threshold: 0.1, TruePositives: 3, FalsePositives: 2,
+			Precision: 3.0 / 5, Recall: 1, F1: 2 * (3.0 / 5) / (3.0/5 + 1)},
+		{Threshold: 0.5, TruePositives: 2, FalsePositives: 1, FalseNegatives: 1, TrueNegatives: 1,
+			Precision: 2.0 / 3, Recall: 2.0 / 3, F1: 2.0 / 3},
+		{Threshold: 0.85, TruePositives: 1, FalseNegatives: 2, TrueNegatives: 2,
+			Precision: 1, Recall: 1.0 / 3, F1: 0.5},
+		// No sample is decided true, so precision is undefined, but F1 is 0
+		// as there are positives which are missed.
+		{Threshold: 0.95, FalseNegatives: 3, TrueNegatives: 2,
+			Precision: nan, Recall: 0, F1: 0},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateNaNs(), cmpopts.EquateApprox(0, 1e-12)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCurve_NoPositives(t *testing.T) {
+	// Without positives or decisions, precision, recall, and F1 are undefined.
+	got := Curve([]Sample{{Score: 0.2, Label: false}}, []float64{0.5})
+	want := []Point{{Threshold: 0.5, TrueNegatives: 1, Precision: math.NaN(), Recall: math.NaN(), F1: math.NaN()}}
+	if diff := cmp.Diff(want, got, cmpopts.EquateNaNs()); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, found := BestF1(got); found {
+		t.Error("got a best F1 of points with undefined F1")
+	}
+}
+
+func TestBestF1(t *testing.T) {
+	points := []Point{
+		{Threshold: 0.1, F1: 0.5},
+		{Threshold: 0.2, F1: math.NaN()},
+		{Threshold: 0.3, F1: 0.8},
+		{Threshold: 0.4, F1: 0.8},
+		{Threshold: 0.5, F1: 0.6},
+	}
+
+	got, found := BestF1(points)
+	if !found || got.Threshold != 0.3 {
+		t.Errorf("got threshold %v (found %v), want the first best 0.3", got.Threshold, found)
+	}
+}
+
+func TestMinPrecision(t *testing.T) {
+	points := []Point{
+		{Threshold: 0.9, Precision: math.NaN()},
+		{Threshold: 0.7, Precision: 0.95},
+		{Threshold: 0.5, Precision: 0.85},
+		{Threshold: 0.8, Precision: 0.99},
+	}
+
+	got, found := MinPrecision(points, 0.9)
+	if !found || got.Threshold != 0.7 {
+		t.Errorf("got threshold %v (found %v), want 0.7", got.Threshold, found)
+	}
+
+	if _, found := MinPrecision(points, 0.999); found {
+		t.Error("got a point, want none to reach precision 0.999")
+	}
+}
@@ -0,0 +1,176 @@
+// Package contexts mines the text around software mentions for identifiers
+// SoftCite's extractor missed: RRIDs, DOIs, Zenodo records, Software Heritage
+// ids, GitHub repositories, and versions.
+package contexts
+
+import (
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
+	"github.com/willbeason/software-mentions/pkg/urls"
+	"github.com/willbeason/software-mentions/pkg/versions"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is the type of identifier.
+type Kind string
+
+const (
+	// KindRrid identifiers are Research Resource Identifiers of software, like
+	// "RRID:SCR_001905".
+	KindRrid Kind = "rrid"
+	// KindDoi identifiers are DOIs other than Zenodo DOIs.
+	KindDoi Kind = "doi"
+	// KindZenodo identifiers are the DOIs of Zenodo records, whether written as
+	// DOIs or as Zenodo record URLs.
+	KindZenodo Kind = "zenodo"
+	// KindSwh identifiers are Software Heritage persistent identifiers, like
+	// "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505".
+	KindSwh Kind = "swh"
+	// KindGithub identifiers are the URLs of GitHub repositories.
+	KindGithub Kind = "github"
+	// KindVersion identifiers are versions, like "version 2.3.1" or "R2019b".
+	KindVersion Kind = "version"
+)
+
+// Kinds are all identifier kinds, in reporting order.
+var Kinds = []Kind{KindRrid, KindDoi, KindZenodo, KindSwh, KindGithub, KindVersion}
+
+// Identifier is an identifier found in a text.
+type Identifier struct {
+	Kind Kind
+	// Value is the normalized identifier.
+	Value string
+	// Raw is the identifier as it appears in the text.
+	Raw string
+	// Start and End are the offsets, in characters rather than bytes, of the
+	// first character of Raw and of the character after it.
+	Start int
+	End   int
+}
+
+// recognizer finds identifiers of a kind. normalize returns the value and
+// kind of a match, or false if the match isn't an identifier after all.
+type recognizer struct {
+	kind      Kind
+	pattern   *regexp.Regexp
+	normalize func(match string) (string, Kind, bool)
+}
+
+// trailingPunctuation is punctuation which ends sentences and parentheticals
+// rather than identifiers.
+const trailingPunctuation = ".,;:)]}>'\""
+
+// recognizers are in order of precedence; identifiers overlapping one found by
+// an earlier recognizer are dropped, so a DOI isn't also mined for a version.
+var recognizers = []recognizer{
+	{
+		kind:    KindSwh,
+		pattern: regexp.MustCompile(`(?i)\bswh:1:(?:cnt|dir|rel|rev|snp):[0-9a-f]{40}\b`),
+		normalize: func(match string) (string, Kind, bool) {
+			return strings.ToLower(match), KindSwh, true
+		},
+	},
+	{
+		kind:    KindRrid,
+		pattern: regexp.MustCompile(`(?i)\b(?:RRID:\s*)?SCR_\d{6}\b`),
+		normalize: func(match string) (string, Kind, bool) {
+			id := match[strings.LastIndex(strings.ToUpper(match), "SCR_"):]
+			return "RRID:" + strings.ToUpper(id), KindRrid, true
+		},
+	},
+	{
+		kind:    KindDoi,
+		pattern: regexp.MustCompile(`(?i)\b10\.\d{4,9}/[^\s"<>]+`),
+		normalize: func(match string) (string, Kind, bool) {
+			doi, err := ids.NormalizeDoi(match)
+			if err != nil {
+				return "", "", false
+			}
+			if strings.HasPrefix(doi, "10.5281/zenodo.") {
+				return doi, KindZenodo, true
+			}
+			return doi, KindDoi, true
+		},
+	},
+	{
+		kind:    KindZenodo,
+		pattern: regexp.MustCompile(`(?i)\bzenodo\.org/records?/\d+`),
+		normalize: func(match string) (string, Kind, bool) {
+			u, err := urls.Parse(match)
+			if err != nil || u.Doi == "" {
+				return "", "", false
+			}
+			return u.Doi, KindZenodo, true
+		},
+	},
+	{
+		kind:    KindGithub,
+		pattern: regexp.MustCompile(`(?i)\bgithub\.com/[\w.-]+/[\w.-]+`),
+		normalize: func(match string) (string, Kind, bool) {
+			u, err := urls.Parse(match)
+			if err != nil || u.Repository == "" {
+				return "", "", false
+			}
+			return u.Repository, KindGithub, true
+		},
+	},
+	{
+		kind: KindVersion,
+		pattern: regexp.MustCompile(`(?i)\bR\d{4}[ab]\b|` +
+			`\b(?:version|ver\.?|v\.?)\s*\d+(?:\.\d+){0,2}(?:[-.]?(?:alpha|beta|rc|a|b)\d*)?\b`),
+		normalize: func(match string) (string, Kind, bool) {
+			version, ok := versions.Parse(match)
+			if !ok {
+				return "", "", false
+			}
+			return version.String(), KindVersion, true
+		},
+	},
+}
+
+// Mine returns the identifiers in text, ordered by Start.
+func Mine(text string) []Identifier {
+	type span struct{ start, end int }
+	var found []span
+	overlaps := func(start, end int) bool {
+		for _, s := range found {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []Identifier
+	for _, r := range recognizers {
+		for _, indices := range r.pattern.FindAllStringIndex(text, -1) {
+			start, end := indices[0], indices[1]
+			raw := strings.TrimRight(text[start:end], trailingPunctuation)
+			end = start + len(raw)
+			if overlaps(start, end) {
+				continue
+			}
+
+			value, kind, ok := r.normalize(raw)
+			if !ok {
+				continue
+			}
+
+			found = append(found, span{start: start, end: end})
+			result = append(result, Identifier{
+				Kind:  kind,
+				Value: value,
+				Raw:   raw,
+				Start: utf8.RuneCountInString(text[:start]),
+				End:   utf8.RuneCountInString(text[:end]),
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
@@ -0,0 +1,116 @@
+package contexts
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestMine(t *testing.T) {
+	tcs := []struct {
+		name string
+		text string
+		want []Identifier
+	}{
+		{
+			name: "none",
+			text: "We analyzed the data in SPSS.",
+			want: nil,
+		},
+		{
+			name: "rrid",
+			text: "ImageJ (RRID: scr_003070) was used.",
+			want: []Identifier{
+				{Kind: KindRrid, Value: "RRID:SCR_003070", Raw: "RRID: scr_003070", Start: 8, End: 24},
+			},
+		},
+		{
+			name: "doi",
+			text: "Available at https://doi.org/10.1000/ABC.123.",
+			want: []Identifier{
+				{Kind: KindDoi, Value: "10.1000/abc.123", Raw: "10.1000/ABC.123", Start: 29, End: 44},
+			},
+		},
+		{
+			name: "zenodo doi",
+			text: "Archived as 10.5281/zenodo.1234567",
+			want: []Identifier{
+				{Kind: KindZenodo, Value: "10.5281/zenodo.1234567", Raw: "10.5281/zenodo.1234567", Start: 12, End: 34},
+			},
+		},
+		{
+			name: "zenodo record",
+			text: "See zenodo.org/record/42).",
+			want: []Identifier{
+				{Kind: KindZenodo, Value: "10.5281/zenodo.42", Raw: "zenodo.org/record/42", Start: 4, End: 24},
+			},
+		},
+		{
+			name: "swh",
+			text: "swh:1:dir:D198BC9D7A6BCF6DB04F476D29314F157507D505",
+			want: []Identifier{
+				{Kind: KindSwh, Value: "swh:1:dir:d198bc9d7a6bcf6db04f476d29314f157507d505",
+					Raw: "swh:1:dir:D198BC9D7A6BCF6DB04F476D29314F157507D505", Start: 0, End: 50},
+			},
+		},
+		{
+			name: "github",
+			text: "Code: https://github.com/Owner/Repo.git, v1.2.",
+			want: []Identifier{
+				{Kind: KindGithub, Value: "https://github.com/owner/repo", Raw: "github.com/Owner/Repo.git", Start: 14, End: 39},
+				{Kind: KindVersion, Value: "1.2", Raw: "v1.2", Start: 41, End: 45},
+			},
+		},
+		{
+			name: "versions",
+			text: "MATLAB R2019b and version 2.3.1-beta2",
+			want: []Identifier{
+				{Kind: KindVersion, Value: "R2019b", Raw: "R2019b", Start: 7, End: 13},
+				{Kind: KindVersion, Value: "2.3.1-beta2", Raw: "version 2.3.1-beta2", Start: 18, End: 37},
+			},
+		},
+		{
+			// The version in the DOI overlaps the DOI, which takes precedence.
+			name: "doi before version",
+			text: "doi:10.1000/tool.v2.1",
+			want: []Identifier{
+				{Kind: KindDoi, Value: "10.1000/tool.v2.1", Raw: "10.1000/tool.v2.1", Start: 4, End: 21},
+			},
+		},
+		{
+			// The Zenodo DOI overlaps the record URL, and takes precedence.
+			name: "doi before zenodo record",
+			text: "https://zenodo.org/record/10.5281/zenodo.99",
+			want: []Identifier{
+				{Kind: KindZenodo, Value: "10.5281/zenodo.99", Raw: "10.5281/zenodo.99", Start: 26, End: 43},
+			},
+		},
+		{
+			// Offsets count characters rather than bytes.
+			name: "rune offsets",
+			text: "Données analysées – v3.0 «RRID:SCR_001905»",
+			want: []Identifier{
+				{Kind: KindVersion, Value: "3.0", Raw: "v3.0", Start: 20, End: 24},
+				{Kind: KindRrid, Value: "RRID:SCR_001905", Raw: "RRID:SCR_001905", Start: 26, End: 41},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Mine(tc.text)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMine_OffsetsSliceText(t *testing.T) {
+	text := "Ünïcödé ✓ github.com/a/b and 10.1000/x"
+	runes := []rune(text)
+	for _, id := range Mine(text) {
+		if got := string(runes[id.Start:id.End]); got != id.Raw {
+			t.Errorf("got %q at [%d, %d), want %q", got, id.Start, id.End, id.Raw)
+		}
+	}
+}
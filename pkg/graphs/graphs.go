@@ -0,0 +1,385 @@
+// Package graphs writes weighted undirected graphs with node and edge
+// attributes as CSV edge lists, GraphML, or GEXF.
+package graphs
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrWriteGraph = errors.New("writing graph")
+
+// AttributeType is the type of the values of an Attribute.
+type AttributeType int
+
+const (
+	Int AttributeType = iota
+	Double
+	String
+)
+
+func (t AttributeType) graphML() string {
+	switch t {
+	case Int:
+		return "long"
+	case Double:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+func (t AttributeType) gexf() string {
+	switch t {
+	case Int:
+		return "long"
+	case Double:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// Attribute is a named value of each node or edge.
+type Attribute struct {
+	Name string
+	Type AttributeType
+}
+
+type Node struct {
+	Id    string
+	Label string
+	// Values are the values of the graph's node attributes, in order.
+	// Empty values are omitted from GraphML and GEXF.
+	Values []string
+}
+
+type Edge struct {
+	Source string
+	Target string
+	Weight float64
+	// Values are the values of the graph's edge attributes, in order.
+	Values []string
+}
+
+// Graph is an undirected graph.
+type Graph struct {
+	NodeAttributes []Attribute
+	EdgeAttributes []Attribute
+
+	Nodes []Node
+	Edges []Edge
+}
+
+const (
+	ExtCsv     = ".csv"
+	ExtGraphML = ".graphml"
+	ExtGexf    = ".gexf"
+)
+
+// CheckPath returns an error if the extension of path is not a known format.
+func CheckPath(path string) error {
+	switch ext := filepath.Ext(path); ext {
+	case ExtCsv, ExtGraphML, ExtGexf:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown graph file extension %q, want one of %s, %s, or %s", ErrWriteGraph, ext, ExtCsv, ExtGraphML, ExtGexf)
+	}
+}
+
+// WriteFile writes g to path in the format of its extension.
+// For ".csv", edges are written to path and nodes to a ".nodes.csv" file next
+// to it.
+func WriteFile(path string, g *Graph) error {
+	err := CheckPath(path)
+	if err != nil {
+		return err
+	}
+
+	var write func(w io.Writer, g *Graph) error
+	switch filepath.Ext(path) {
+	case ExtCsv:
+		nodesPath := strings.TrimSuffix(path, ExtCsv) + ".nodes" + ExtCsv
+		err := writeFile(nodesPath, g, WriteNodesCsv)
+		if err != nil {
+			return err
+		}
+		write = WriteEdgesCsv
+	case ExtGraphML:
+		write = WriteGraphML
+	case ExtGexf:
+		write = WriteGexf
+	}
+
+	return writeFile(path, g, write)
+}
+
+func writeFile(path string, g *Graph, write func(w io.Writer, g *Graph) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w: creating %q: %w", ErrWriteGraph, path, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	err = write(file, g)
+	if err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrWriteGraph, path, err)
+	}
+
+	return nil
+}
+
+// WriteEdgesCsv writes the edges of g with columns source, target, weight,
+// and the edge attributes.
+func WriteEdgesCsv(w io.Writer, g *Graph) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"source", "target", "weight"}
+	for _, attribute := range g.EdgeAttributes {
+		header = append(header, attribute.Name)
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, edge := range g.Edges {
+		row := append([]string{edge.Source, edge.Target, formatWeight(edge.Weight)}, edge.Values...)
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteNodesCsv writes the nodes of g with columns id, label, and the node
+// attributes.
+func WriteNodesCsv(w io.Writer, g *Graph) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"id", "label"}
+	for _, attribute := range g.NodeAttributes {
+		header = append(header, attribute.Name)
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range g.Nodes {
+		row := append([]string{node.Id, node.Label}, node.Values...)
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type graphMLKey struct {
+	Id   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	Id   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// WriteGraphML writes g as GraphML, with the node label, edge weight, and
+// attributes as data keys.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphML{Xmlns: "http://graphml.graphdrawing.org/xmlns"}
+	doc.Graph.EdgeDefault = "undirected"
+
+	doc.Keys = append(doc.Keys,
+		graphMLKey{Id: "label", For: "node", Name: "label", Type: "string"},
+		graphMLKey{Id: "weight", For: "edge", Name: "weight", Type: "double"})
+	for i, attribute := range g.NodeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{Id: "n" + strconv.Itoa(i), For: "node", Name: attribute.Name, Type: attribute.Type.graphML()})
+	}
+	for i, attribute := range g.EdgeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{Id: "e" + strconv.Itoa(i), For: "edge", Name: attribute.Name, Type: attribute.Type.graphML()})
+	}
+
+	for _, node := range g.Nodes {
+		n := graphMLNode{Id: node.Id, Data: []graphMLData{{Key: "label", Value: node.Label}}}
+		for i, value := range node.Values {
+			if value != "" {
+				n.Data = append(n.Data, graphMLData{Key: "n" + strconv.Itoa(i), Value: value})
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+	}
+
+	for _, edge := range g.Edges {
+		e := graphMLEdge{Source: edge.Source, Target: edge.Target, Data: []graphMLData{{Key: "weight", Value: formatWeight(edge.Weight)}}}
+		for i, value := range edge.Values {
+			if value != "" {
+				e.Data = append(e.Data, graphMLData{Key: "e" + strconv.Itoa(i), Value: value})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, e)
+	}
+
+	return writeXml(w, doc)
+}
+
+type gexfAttribute struct {
+	Id    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// gexfValues is a pointer in nodes and edges so that it is omitted when nil,
+// since encoding/xml writes the parent of an empty "attvalues>attvalue" slice
+// and GEXF requires at least one attvalue in an attvalues element.
+type gexfValues struct {
+	Values []gexfValue `xml:"attvalue"`
+}
+
+type gexfNode struct {
+	Id     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr"`
+	Values *gexfValues `xml:"attvalues,omitempty"`
+}
+
+type gexfEdge struct {
+	Id     string      `xml:"id,attr"`
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Weight string      `xml:"weight,attr"`
+	Values *gexfValues `xml:"attvalues,omitempty"`
+}
+
+type gexf struct {
+	XMLName xml.Name `xml:"gexf"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// WriteGexf writes g as GEXF 1.3.
+func WriteGexf(w io.Writer, g *Graph) error {
+	doc := gexf{Xmlns: "http://gexf.net/1.3", Version: "1.3"}
+	doc.Graph.DefaultEdgeType = "undirected"
+
+	nodeAttributes := gexfAttributes{Class: "node"}
+	for i, attribute := range g.NodeAttributes {
+		nodeAttributes.Attributes = append(nodeAttributes.Attributes, gexfAttribute{Id: strconv.Itoa(i), Title: attribute.Name, Type: attribute.Type.gexf()})
+	}
+	edgeAttributes := gexfAttributes{Class: "edge"}
+	for i, attribute := range g.EdgeAttributes {
+		edgeAttributes.Attributes = append(edgeAttributes.Attributes, gexfAttribute{Id: strconv.Itoa(i), Title: attribute.Name, Type: attribute.Type.gexf()})
+	}
+	for _, attributes := range []gexfAttributes{nodeAttributes, edgeAttributes} {
+		if len(attributes.Attributes) > 0 {
+			doc.Graph.Attributes = append(doc.Graph.Attributes, attributes)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		n := gexfNode{Id: node.Id, Label: node.Label, Values: newGexfValues(node.Values)}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+	}
+
+	for i, edge := range g.Edges {
+		e := gexfEdge{Id: strconv.Itoa(i), Source: edge.Source, Target: edge.Target, Weight: formatWeight(edge.Weight),
+			Values: newGexfValues(edge.Values)}
+		doc.Graph.Edges = append(doc.Graph.Edges, e)
+	}
+
+	return writeXml(w, doc)
+}
+
+// newGexfValues returns the non-empty values, or nil if there are none.
+func newGexfValues(values []string) *gexfValues {
+	var result *gexfValues
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		if result == nil {
+			result = &gexfValues{}
+		}
+		result.Values = append(result.Values, gexfValue{For: strconv.Itoa(i), Value: value})
+	}
+	return result
+}
+
+func writeXml(w io.Writer, doc any) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'g', -1, 64)
+}
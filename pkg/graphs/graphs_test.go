@@ -0,0 +1,124 @@
+package graphs
+
+import (
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"testing"
+)
+
+func testGraph() *Graph {
+	return &Graph{
+		NodeAttributes: []Attribute{{Name: "papers", Type: Int}, {Name: "first_year", Type: String}},
+		EdgeAttributes: []Attribute{{Name: "npmi", Type: Double}},
+		Nodes: []Node{
+			{Id: "0", Label: `R & "Rcpp" <core>`, Values: []string{"12", "2004"}},
+			{Id: "1", Label: "SPSS's", Values: []string{"3", ""}},
+		},
+		Edges: []Edge{
+			{Source: "0", Target: "1", Weight: 2.5, Values: []string{"0.125"}},
+			{Source: "1", Target: "0", Weight: 1e-7, Values: []string{""}},
+		},
+	}
+}
+
+const wantGraphML = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"></key>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"></key>
+  <key id="n0" for="node" attr.name="papers" attr.type="long"></key>
+  <key id="n1" for="node" attr.name="first_year" attr.type="string"></key>
+  <key id="e0" for="edge" attr.name="npmi" attr.type="double"></key>
+  <graph edgedefault="undirected">
+    <node id="0">
+      <data key="label">R &amp; &#34;Rcpp&#34; &lt;core&gt;</data>
+      <data key="n0">12</data>
+      <data key="n1">2004</data>
+    </node>
+    <node id="1">
+      <data key="label">SPSS&#39;s</data>
+      <data key="n0">3</data>
+    </node>
+    <edge source="0" target="1">
+      <data key="weight">2.5</data>
+      <data key="e0">0.125</data>
+    </edge>
+    <edge source="1" target="0">
+      <data key="weight">1e-07</data>
+    </edge>
+  </graph>
+</graphml>
+`
+
+const wantGexf = `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://gexf.net/1.3" version="1.3">
+  <graph defaultedgetype="undirected">
+    <attributes class="node">
+      <attribute id="0" title="papers" type="long"></attribute>
+      <attribute id="1" title="first_year" type="string"></attribute>
+    </attributes>
+    <attributes class="edge">
+      <attribute id="0" title="npmi" type="double"></attribute>
+    </attributes>
+    <nodes>
+      <node id="0" label="R &amp; &#34;Rcpp&#34; &lt;core&gt;">
+        <attvalues>
+          <attvalue for="0" value="12"></attvalue>
+          <attvalue for="1" value="2004"></attvalue>
+        </attvalues>
+      </node>
+      <node id="1" label="SPSS&#39;s">
+        <attvalues>
+          <attvalue for="0" value="3"></attvalue>
+        </attvalues>
+      </node>
+    </nodes>
+    <edges>
+      <edge id="0" source="0" target="1" weight="2.5">
+        <attvalues>
+          <attvalue for="0" value="0.125"></attvalue>
+        </attvalues>
+      </edge>
+      <edge id="1" source="1" target="0" weight="1e-07"></edge>
+    </edges>
+  </graph>
+</gexf>
+`
+
+const wantEdgesCsv = `source,target,weight,npmi
+0,1,2.5,0.125
+1,0,1e-07,
+`
+
+const wantNodesCsv = `id,label,papers,first_year
+0,"R & ""Rcpp"" <core>",12,2004
+1,SPSS's,3,
+`
+
+func TestWrite(t *testing.T) {
+	// Labels need escaping, empty values are omitted, and weights are written
+	// in the shortest form that reads back exactly.
+	tcs := []struct {
+		name  string
+		write func(w io.Writer, g *Graph) error
+		want  string
+	}{
+		{name: "graphml", write: WriteGraphML, want: wantGraphML},
+		{name: "gexf", write: WriteGexf, want: wantGexf},
+		{name: "edges csv", write: WriteEdgesCsv, want: wantEdgesCsv},
+		{name: "nodes csv", write: WriteNodesCsv, want: wantNodesCsv},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := tc.write(&b, testGraph())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
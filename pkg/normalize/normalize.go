@@ -0,0 +1,555 @@
+// Package normalize clusters the names software is mentioned by, such as
+// "SPSS", "IBM SPSS", and "SPSS Statistics", and chooses a canonical name for
+// each cluster.
+package normalize
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultOverrides are curated aliases which the rules miss or get wrong.
+//
+//go:embed aliases.csv
+var defaultOverrides []byte
+
+var ErrOverrides = errors.New("reading software alias overrides")
+
+// Rule is the reason an alias is in the cluster of its canonical name.
+type Rule string
+
+const (
+	// RuleCanonical is the rule of canonical names themselves.
+	RuleCanonical Rule = "canonical"
+	// RuleOverride aliases are from the override file.
+	RuleOverride Rule = "override"
+	// RuleCase aliases differ from another name only in case, spacing, and
+	// punctuation.
+	RuleCase Rule = "case"
+	// RuleWikidata aliases share a wikidata id with another name.
+	RuleWikidata Rule = "wikidata"
+	// RuleVendor aliases differ from another name by a vendor prefix, such as
+	// "IBM". Generic suffixes, such as "Statistics", are left to the overrides,
+	// since stripping them joins unrelated names.
+	RuleVendor Rule = "vendor"
+	// RuleEditDistance aliases are one edit, or a transposition, from a name
+	// mentioned at least typoRatio times as often, like a typo of it.
+	RuleEditDistance Rule = "edit_distance"
+)
+
+// Confidence is how likely an alias joined by the rule names the same software.
+func (r Rule) Confidence() float64 {
+	switch r {
+	case RuleCanonical, RuleOverride:
+		return 1.0
+	case RuleCase:
+		return 0.95
+	case RuleWikidata:
+		return 0.9
+	case RuleVendor:
+		return 0.8
+	case RuleEditDistance:
+		return 0.6
+	default:
+		panic("invalid rule " + r)
+	}
+}
+
+// vendorPrefixes are the vendor names stripped by VendorKey. Abbreviations
+// such as "MS" are left out, as "MS" also abbreviates mass spectrometry, so
+// names like "MS Excel" are in the overrides instead.
+var vendorPrefixes = []string{"the mathworks", "mathworks", "microsoft", "ibm", "adobe", "graphpad"}
+
+const (
+	// minEditLength is the length of the shortest key joined by edit distance,
+	// so short names like "SAS" and "SPS" stay apart.
+	minEditLength = 6
+	// typoRatio is how many times more often a name must be mentioned than a
+	// name one edit away for that name to be taken as its typo.
+	typoRatio = 10
+)
+
+// Key folds case and collapses runs of spaces and punctuation in s, so
+// "GraphPad-Prism" and "graphpad prism" have the same key.
+func Key(s string) string {
+	var sb strings.Builder
+	separator := false
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			separator = true
+			continue
+		}
+		if separator && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		separator = false
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// VendorKey is the Key of s without a vendor prefix, so "IBM SPSS" has the
+// same vendor key as "SPSS".
+func VendorKey(s string) string {
+	key := Key(s)
+	for _, prefix := range vendorPrefixes {
+		if rest, found := strings.CutPrefix(key, prefix+" "); found {
+			return rest
+		}
+	}
+	return key
+}
+
+// Overrides map aliases to curated canonical names. An alias with an entry
+// mapping it to itself is kept out of every cluster, to split names the rules
+// join wrongly.
+type Overrides struct {
+	exact map[string]string
+	fuzzy map[string]string
+}
+
+// NewOverrides returns the default overrides.
+func NewOverrides() *Overrides {
+	o := &Overrides{
+		exact: make(map[string]string),
+		fuzzy: make(map[string]string),
+	}
+
+	err := o.Load(bytes.NewReader(defaultOverrides))
+	if err != nil {
+		panic(err)
+	}
+
+	return o
+}
+
+// LoadOverrides returns the default overrides extended by the entries in the
+// CSV file at path, if path is not empty.
+func LoadOverrides(path string) (*Overrides, error) {
+	o := NewOverrides()
+	if path == "" {
+		return o, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %q: %w", ErrOverrides, path, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	err = o.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrOverrides, path, err)
+	}
+
+	return o, nil
+}
+
+// Load adds the entries of a CSV with the header "alias,canonical", replacing
+// existing entries for the same aliases.
+func (o *Overrides) Load(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading header: %w", ErrOverrides, err)
+	}
+	if header[0] != "alias" || header[1] != "canonical" {
+		return fmt.Errorf("%w: got header %v but want [alias canonical]", ErrOverrides, header)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %w", ErrOverrides, err)
+		}
+
+		if record[0] == "" || record[1] == "" {
+			return fmt.Errorf("%w: empty alias or canonical name in %v", ErrOverrides, record)
+		}
+
+		o.exact[record[0]] = record[1]
+		o.fuzzy[Key(record[0])] = record[1]
+	}
+
+	return nil
+}
+
+// pinned returns whether alias has an entry mapping it to itself.
+func (o *Overrides) pinned(alias string) bool {
+	return o.exact[alias] == alias
+}
+
+// Lookup returns the canonical name of alias, or false if it has no override.
+// Aliases are matched exactly first, then by Key.
+func (o *Overrides) Lookup(alias string) (string, bool) {
+	if canonical, found := o.exact[alias]; found {
+		return canonical, true
+	}
+
+	canonical, found := o.fuzzy[Key(alias)]
+	return canonical, found
+}
+
+// Alias is a name of a software and the canonical name of its cluster.
+type Alias struct {
+	Alias     string
+	Canonical string
+	// Confidence is that of the weakest rule joining Alias to Canonical.
+	Confidence float64
+	// Rule is the weakest rule joining Alias to Canonical.
+	Rule Rule
+	// Mentions is the number of mentions of Alias.
+	Mentions int
+}
+
+// Clusterer counts the names software is mentioned by.
+type Clusterer struct {
+	ids   map[string]int
+	names []*name
+}
+
+type name struct {
+	name     string
+	mentions int
+	wikidata map[string]int
+}
+
+func NewClusterer() *Clusterer {
+	return &Clusterer{ids: make(map[string]int)}
+}
+
+// Add counts a mention of software, with its wikidata id if it has one.
+func (c *Clusterer) Add(software, wikidataId string) {
+	id, found := c.ids[software]
+	if !found {
+		id = len(c.names)
+		c.ids[software] = id
+		c.names = append(c.names, &name{name: software})
+	}
+
+	n := c.names[id]
+	n.mentions++
+	if wikidataId != "" {
+		if n.wikidata == nil {
+			n.wikidata = make(map[string]int)
+		}
+		n.wikidata[wikidataId]++
+	}
+}
+
+// wikidataId returns the wikidata id n is most often mentioned with.
+func (n *name) wikidataId() string {
+	result := ""
+	count := 0
+	for id, c := range n.wikidata {
+		if c > count || c == count && id < result {
+			result = id
+			count = c
+		}
+	}
+	return result
+}
+
+type edge struct {
+	from, to int
+	rule     Rule
+}
+
+// Cluster joins the names of the software counted so far by the rules, in
+// decreasing order of confidence, and chooses as the canonical name of each
+// cluster its override target or else its most mentioned name.
+func (c *Clusterer) Cluster(overrides *Overrides) *Aliases {
+	n := len(c.names)
+
+	// Names with overrides to other names are mapped directly, so keep them
+	// out of the rules. Override targets anchor their clusters.
+	excluded := make([]bool, n)
+	isTarget := make([]bool, n)
+	for i, nm := range c.names {
+		canonical, found := overrides.Lookup(nm.name)
+		if !found {
+			continue
+		}
+		excluded[i] = canonical != nm.name || overrides.pinned(nm.name)
+		if target, found := c.ids[canonical]; found {
+			isTarget[target] = true
+		}
+	}
+
+	var edges []edge
+	group := func(keyOf func(i int) string, rule Rule) {
+		representatives := make(map[string]int)
+		for i := range n {
+			if excluded[i] {
+				continue
+			}
+			key := keyOf(i)
+			if key == "" {
+				continue
+			}
+			if representative, found := representatives[key]; found {
+				edges = append(edges, edge{from: i, to: representative, rule: rule})
+			} else {
+				representatives[key] = i
+			}
+		}
+	}
+
+	group(func(i int) string { return Key(c.names[i].name) }, RuleCase)
+	group(func(i int) string { return c.names[i].wikidataId() }, RuleWikidata)
+	group(func(i int) string { return VendorKey(c.names[i].name) }, RuleVendor)
+	edges = append(edges, c.typoEdges(excluded)...)
+
+	// Kruskal's algorithm: joining in decreasing order of confidence makes
+	// the joins a maximum spanning forest, so the path between two names in
+	// it has the strongest weakest rule of any path.
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].rule.Confidence() > edges[j].rule.Confidence()
+	})
+	parents := make([]int, n)
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	forest := make([][]edge, n)
+	for _, e := range edges {
+		from, to := find(e.from), find(e.to)
+		if from == to {
+			continue
+		}
+		parents[from] = to
+		forest[e.from] = append(forest[e.from], e)
+		forest[e.to] = append(forest[e.to], edge{from: e.to, to: e.from, rule: e.rule})
+	}
+
+	// Choose the canonical name of each cluster.
+	canonicals := make(map[int]int)
+	for i, nm := range c.names {
+		root := find(i)
+		best, found := canonicals[root]
+		if !found || isTarget[i] && !isTarget[best] ||
+			isTarget[i] == isTarget[best] && (nm.mentions > c.names[best].mentions ||
+				nm.mentions == c.names[best].mentions && nm.name < c.names[best].name) {
+			canonicals[root] = i
+		}
+	}
+
+	result := &Aliases{byAlias: make(map[string]Alias, n)}
+	for _, canonical := range canonicals {
+		// Walk the tree of the cluster from its canonical name, tracking the
+		// weakest rule on the path to each name.
+		weakest := map[int]Rule{canonical: RuleCanonical}
+		stack := []int{canonical}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, e := range forest[i] {
+				if _, visited := weakest[e.to]; visited {
+					continue
+				}
+				rule := e.rule
+				if weakest[i].Confidence() < rule.Confidence() {
+					rule = weakest[i]
+				}
+				weakest[e.to] = rule
+				stack = append(stack, e.to)
+			}
+		}
+
+		for i, rule := range weakest {
+			result.byAlias[c.names[i].name] = Alias{
+				Alias:      c.names[i].name,
+				Canonical:  c.names[canonical].name,
+				Confidence: rule.Confidence(),
+				Rule:       rule,
+				Mentions:   c.names[i].mentions,
+			}
+		}
+	}
+
+	for _, nm := range c.names {
+		canonical, found := overrides.Lookup(nm.name)
+		if !found || canonical == nm.name {
+			continue
+		}
+		result.byAlias[nm.name] = Alias{
+			Alias:      nm.name,
+			Canonical:  canonical,
+			Confidence: RuleOverride.Confidence(),
+			Rule:       RuleOverride,
+			Mentions:   nm.mentions,
+		}
+	}
+
+	return result
+}
+
+// typoEdges joins the most mentioned name of each vendor key to that of a
+// vendor key one edit away which is mentioned at least typoRatio times as
+// often. Keys with digits are left apart, as "Python2" is no typo of "Python3".
+func (c *Clusterer) typoEdges(excluded []bool) []edge {
+	// The most mentioned name and total mentions of each vendor key.
+	type keyStats struct {
+		key      []rune
+		name     int
+		mentions int
+	}
+	var keys []*keyStats
+	byKey := make(map[string]*keyStats)
+	for i, nm := range c.names {
+		if excluded[i] {
+			continue
+		}
+		key := VendorKey(nm.name)
+		if len([]rune(key)) < minEditLength || strings.IndexFunc(key, unicode.IsDigit) >= 0 {
+			continue
+		}
+
+		stats, found := byKey[key]
+		if !found {
+			stats = &keyStats{key: []rune(key), name: i}
+			byKey[key] = stats
+			keys = append(keys, stats)
+		}
+		stats.mentions += nm.mentions
+		if nm.mentions > c.names[stats.name].mentions {
+			stats.name = i
+		}
+	}
+
+	// Keys one edit apart share a key with one rune deleted, or one is the
+	// other with a rune deleted.
+	deletions := make(map[string][]int)
+	for i, stats := range keys {
+		deletions[string(stats.key)] = append(deletions[string(stats.key)], i)
+		for j := range stats.key {
+			deleted := string(stats.key[:j]) + string(stats.key[j+1:])
+			deletions[deleted] = append(deletions[deleted], i)
+		}
+	}
+
+	var result []edge
+	seen := make(map[[2]int]bool)
+	for _, candidates := range deletions {
+		for x, i := range candidates {
+			for _, j := range candidates[x+1:] {
+				if i == j || seen[[2]int{i, j}] {
+					continue
+				}
+				seen[[2]int{i, j}] = true
+
+				rare, common := keys[i], keys[j]
+				if rare.mentions > common.mentions {
+					rare, common = common, rare
+				}
+				if rare.mentions*typoRatio > common.mentions || !oneEdit(rare.key, common.key) {
+					continue
+				}
+				result = append(result, edge{from: rare.name, to: common.name, rule: RuleEditDistance})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].from != result[j].from {
+			return result[i].from < result[j].from
+		}
+		return result[i].to < result[j].to
+	})
+
+	return result
+}
+
+// oneEdit returns whether a and b differ by exactly one insertion, deletion,
+// substitution, or transposition of adjacent runes.
+func oneEdit(a, b []rune) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) < len(b) {
+		return string(a[i:]) == string(b[i+1:])
+	}
+	if i == len(a) {
+		return false
+	}
+	if string(a[i+1:]) == string(b[i+1:]) {
+		return true
+	}
+	return i+1 < len(a) && a[i] == b[i+1] && a[i+1] == b[i] && string(a[i+2:]) == string(b[i+2:])
+}
+
+// SoftwareId returns the stable id of the software with the canonical name.
+func SoftwareId(canonical string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(canonical))
+	return h.Sum64()
+}
+
+// Aliases map the names of software to the canonical names of their clusters.
+type Aliases struct {
+	byAlias map[string]Alias
+}
+
+// Canonical returns the canonical name of software, or software itself if it
+// is not a known alias.
+func (a *Aliases) Canonical(software string) string {
+	if alias, found := a.byAlias[software]; found {
+		return alias.Canonical
+	}
+	return software
+}
+
+// List returns the aliases ordered by canonical name, then by decreasing
+// mentions, then by alias.
+func (a *Aliases) List() []Alias {
+	result := make([]Alias, 0, len(a.byAlias))
+	for _, alias := range a.byAlias {
+		result = append(result, alias)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		left, right := result[i], result[j]
+		if left.Canonical != right.Canonical {
+			return left.Canonical < right.Canonical
+		}
+		if left.Mentions != right.Mentions {
+			return left.Mentions > right.Mentions
+		}
+		return left.Alias < right.Alias
+	})
+
+	return result
+}
@@ -0,0 +1,241 @@
+package normalize
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	tcs := []struct {
+		s    string
+		want string
+	}{
+		{s: "", want: ""},
+		{s: "SPSS", want: "spss"},
+		{s: "GraphPad-Prism", want: "graphpad prism"},
+		{s: "  graphpad   prism ", want: "graphpad prism"},
+		{s: "MS-GF+", want: "ms gf"},
+		{s: "Python3", want: "python3"},
+		{s: "Ärzte/Statistik", want: "ärzte statistik"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.s, func(t *testing.T) {
+			if got := Key(tc.s); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVendorKey(t *testing.T) {
+	tcs := []struct {
+		s    string
+		want string
+	}{
+		{s: "IBM SPSS", want: "spss"},
+		{s: "The MathWorks MATLAB", want: "matlab"},
+		{s: "Microsoft Excel", want: "excel"},
+		{s: "GraphPad Prism", want: "prism"},
+		// Only whole words are vendors.
+		{s: "IBMX", want: "ibmx"},
+		// A vendor alone is not stripped.
+		{s: "Microsoft", want: "microsoft"},
+		// "MS" is not a vendor, as it also abbreviates mass spectrometry.
+		{s: "MS-GF+", want: "ms gf"},
+		{s: "MS Amanda", want: "ms amanda"},
+		// Generic suffixes are left to the overrides.
+		{s: "SPSS Statistics", want: "spss statistics"},
+		{s: "Stata Statistical Software", want: "stata statistical software"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.s, func(t *testing.T) {
+			if got := VendorKey(tc.s); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOneEdit(t *testing.T) {
+	tcs := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "imagej", b: "imagej", want: false},
+		{a: "imagej", b: "imagj", want: true},
+		{a: "imagej", b: "imageji", want: true},
+		{a: "imagej", b: "imagek", want: true},
+		{a: "imagej", b: "imaegj", want: true},
+		{a: "imagej", b: "imgj", want: false},
+		{a: "imagej", b: "jegami", want: false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			if got := oneEdit([]rune(tc.a), []rune(tc.b)); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// cluster clusters names, each mentioned the given number of times, with the
+// wikidata ids after a "|" in the name.
+func cluster(t *testing.T, overrides string, names map[string]int) map[string]Alias {
+	t.Helper()
+
+	o := &Overrides{exact: make(map[string]string), fuzzy: make(map[string]string)}
+	err := o.Load(strings.NewReader("alias,canonical\n" + overrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClusterer()
+	for name, mentions := range names {
+		software, wikidataId, _ := strings.Cut(name, "|")
+		for range mentions {
+			c.Add(software, wikidataId)
+		}
+	}
+
+	result := make(map[string]Alias)
+	for _, alias := range c.Cluster(o).List() {
+		result[alias.Alias] = alias
+	}
+	return result
+}
+
+func TestClusterer_Cluster(t *testing.T) {
+	tcs := []struct {
+		name      string
+		overrides string
+		names     map[string]int
+		// want maps each name to its canonical name and rule.
+		want map[string][2]string
+	}{
+		{
+			name:  "case",
+			names: map[string]int{"ImageJ": 10, "imagej": 2, "ImageJ.": 1},
+			want: map[string][2]string{
+				"ImageJ":  {"ImageJ", "canonical"},
+				"imagej":  {"ImageJ", "case"},
+				"ImageJ.": {"ImageJ", "case"},
+			},
+		},
+		{
+			name:  "wikidata",
+			names: map[string]int{"R|Q206904": 10, "R language|Q206904": 1},
+			want: map[string][2]string{
+				"R":          {"R", "canonical"},
+				"R language": {"R", "wikidata"},
+			},
+		},
+		{
+			name:  "vendor",
+			names: map[string]int{"SPSS": 10, "IBM SPSS": 3},
+			want: map[string][2]string{
+				"SPSS":     {"SPSS", "canonical"},
+				"IBM SPSS": {"SPSS", "vendor"},
+			},
+		},
+		{
+			name:  "no ms vendor",
+			names: map[string]int{"GF": 10, "MS-GF+": 3, "Amanda": 5, "MS Amanda": 2},
+			want: map[string][2]string{
+				"GF":        {"GF", "canonical"},
+				"MS-GF+":    {"MS-GF+", "canonical"},
+				"Amanda":    {"Amanda", "canonical"},
+				"MS Amanda": {"MS Amanda", "canonical"},
+			},
+		},
+		{
+			name:  "no generic suffix",
+			names: map[string]int{"Stata": 10, "Stata Software": 3},
+			want: map[string][2]string{
+				"Stata":          {"Stata", "canonical"},
+				"Stata Software": {"Stata Software", "canonical"},
+			},
+		},
+		{
+			name:  "edit distance",
+			names: map[string]int{"Cytoscape": 100, "Cytoscpae": 2, "Cytoscapes": 20},
+			want: map[string][2]string{
+				"Cytoscape":  {"Cytoscape", "canonical"},
+				"Cytoscpae":  {"Cytoscape", "edit_distance"},
+				"Cytoscapes": {"Cytoscapes", "canonical"},
+			},
+		},
+		{
+			name:  "edit distance skips short names and digits",
+			names: map[string]int{"SAS": 100, "SPS": 1, "Python3": 100, "Python2": 1},
+			want: map[string][2]string{
+				"SAS":     {"SAS", "canonical"},
+				"SPS":     {"SPS", "canonical"},
+				"Python3": {"Python3", "canonical"},
+				"Python2": {"Python2", "canonical"},
+			},
+		},
+		{
+			name:      "override",
+			overrides: "SPSS Statistics,SPSS\n",
+			names:     map[string]int{"SPSS": 1, "SPSS Statistics": 10, "spss statistics": 5},
+			want: map[string][2]string{
+				"SPSS":            {"SPSS", "canonical"},
+				"SPSS Statistics": {"SPSS", "override"},
+				"spss statistics": {"SPSS", "override"},
+			},
+		},
+		{
+			name:      "pinned",
+			overrides: "IBM SPSS,IBM SPSS\n",
+			names:     map[string]int{"SPSS": 10, "IBM SPSS": 3},
+			want: map[string][2]string{
+				"SPSS":     {"SPSS", "canonical"},
+				"IBM SPSS": {"IBM SPSS", "canonical"},
+			},
+		},
+		{
+			name:  "weakest rule on path",
+			names: map[string]int{"Prism": 10, "GraphPad Prism": 3, "graphpad prism": 1},
+			want: map[string][2]string{
+				"Prism":          {"Prism", "canonical"},
+				"GraphPad Prism": {"Prism", "vendor"},
+				"graphpad prism": {"Prism", "vendor"},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			aliases := cluster(t, tc.overrides, tc.names)
+
+			got := make(map[string][2]string)
+			for name, alias := range aliases {
+				got[name] = [2]string{alias.Canonical, string(alias.Rule)}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAliases_Canonical(t *testing.T) {
+	c := NewClusterer()
+	c.Add("MS Excel", "")
+	c.Add("Excel", "")
+	aliases := c.Cluster(NewOverrides())
+
+	for software, want := range map[string]string{
+		"MS Excel": "Excel",
+		"Excel":    "Excel",
+		"unseen":   "unseen",
+	} {
+		if got := aliases.Canonical(software); got != want {
+			t.Errorf("got canonical name of %q %q, want %q", software, got, want)
+		}
+	}
+}
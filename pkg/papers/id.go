@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"strconv"
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
 	"strings"
 )
 
@@ -97,47 +97,19 @@ func ToResourceStrings(resources []ResourceType) ([]string, error) {
 	return result, nil
 }
 
-// ToLicenseType converts a string to the corresponding LicenseType enum.
-// Does not preserve case.
+// ToLicenseType converts a string to the corresponding LicenseType enum using
+// the default license mapping. Does not preserve case.
 func ToLicenseType(licenseType string) (LicenseType, error) {
-	switch licenseType {
-	case "":
+	if licenseType == "" {
 		return LicenseType_LICENSE_UNSPECIFIED, nil
-	case "cc-by", "CC BY":
-		return LicenseType_LICENSE_CC_BY, nil
-	case "cc-by-nc-nd", "CC BY-NC-ND":
-		return LicenseType_LICENSE_CC_BY_NC_ND, nil
-	case "cc-by-nc", "CC BY-NC":
-		return LicenseType_LICENSE_CC_BY_NC, nil
-	case "arXiv":
-		return LicenseType_LICENCE_ARXIV, nil
-	case "cc-by-nc-sa", "CC BY-NC-SA":
-		return LicenseType_LICENSE_CC_BY_NC_SA, nil
-	case "cc-by-sa", "CC BY-SA":
-		return LicenseType_LICENSE_CC_BY_SA, nil
-	case "cc0", "CC0":
-		return LicenseType_LICENSE_CC0, nil
-	case "elsevier-specific: oa user license":
-		return LicenseType_LICENSE_ELSEVIER_SPECIFIC_OA_USER_LICENSE, nil
-	case "implied-oa":
-		return LicenseType_LICENSE_IMPLIED_OA, nil
-	case "pd":
-		return LicenseType_LICENSE_PUBLIC_DOMAIN, nil
-	case "NO-CC CODE":
-		return LicenseType_LICENSE_NO_CC_CODE, nil
-	case "cc-by-nd", "CC BY-ND":
-		return LicenseType_LICENSE_CC_BY_ND, nil
-	case "publisher-specific license":
-		return LicenseType_LICENSE_PUBLISHER_SPECIFIC_LICENSE, nil
-	case "publisher-specific, author manuscript":
-		return LicenseType_LICENSE_PUBLISHER_SPECIFIC_AUTHOR_MANUSCRIPT, nil
-	case "acs-specific: authorchoice/editors choice usage agreement":
-		return LicenseType_LICENSE_ACS_SPECIFIC_CHOICE_USAGE_AGREEMENT, nil
-	case "Open Government Licence - Canada":
-		return LicenseType_LICENSE_OPEN_GOVERNMENT_LICENSE_CANADA, nil
-	default:
+	}
+
+	license, found := defaultLicenseMapping().Lookup(licenseType)
+	if !found {
 		return LicenseType_LICENSE_UNSPECIFIED, fmt.Errorf("%w: unknown license %q", ErrParsePaperId, licenseType)
 	}
+
+	return license.Type, nil
 }
 
 func ToLicenseString(licenseType LicenseType) (string, error) {
@@ -187,13 +159,12 @@ func ToPmid(id string) (*Pmid, error) {
 	}
 
 	// Example: 12862144
-
-	result, err := strconv.ParseInt(id, 10, 32)
+	result, err := ids.ParsePmid(id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing PmId %q: %w", ErrParsePaperId, id, err)
+		return nil, fmt.Errorf("%w: parsing PmId: %w", ErrParsePaperId, err)
 	}
 
-	return &Pmid{Id: uint32(result)}, nil
+	return &Pmid{Id: result}, nil
 }
 
 func PmidToString(id *Pmid) string {
@@ -209,15 +180,13 @@ func ToPmcid(id string) (*Pmcid, error) {
 		return nil, nil
 	}
 
-	// Example: PMC6665909
-	id = id[3:]
-
-	result, err := strconv.ParseInt(id, 10, 32)
+	// Example: PMC6665909 or, if versioned, PMC6665909.2
+	result, err := ids.ParsePmcid(id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing PmcId %q: %w", ErrParsePaperId, id, err)
+		return nil, fmt.Errorf("%w: parsing PmcId: %w", ErrParsePaperId, err)
 	}
 
-	return &Pmcid{Id: uint32(result)}, nil
+	return &Pmcid{Id: result.Id, Version: result.Version}, nil
 }
 
 func PmcidToString(id *Pmcid) string {
@@ -225,23 +194,25 @@ func PmcidToString(id *Pmcid) string {
 		return ""
 	}
 
-	return fmt.Sprintf("PMC%d", id.Id)
+	return ids.Pmcid{Id: id.Id, Version: id.Version}.String()
 }
 
 func ToIstexId(id string) (*IstexId, error) {
 	if id == "" {
 		return nil, nil
 	}
-	if len(id) != 40 {
-		return nil, fmt.Errorf("%w: IstexId must be 40 characters long, got %d", ErrParsePaperId, len(id))
-	}
 
 	// Example: 4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A1
+	normalized, err := ids.NormalizeIstexId(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing IstexId: %w", ErrParsePaperId, err)
+	}
+
 	result := &IstexId{
-		Id: make([]byte, 20),
+		Id: make([]byte, ids.IstexIdLength/2),
 	}
 
-	_, err := hex.Decode(result.Id, []byte(id))
+	_, err = hex.Decode(result.Id, []byte(normalized))
 	if err != nil {
 		return nil, fmt.Errorf("%w: parsing IstexId %q: %w", ErrParsePaperId, id, err)
 	}
@@ -260,12 +231,67 @@ func IstexIdToString(id *IstexId) string {
 
 var ErrParsePaperIdJson = errors.New("parsing PaperId from JSON")
 
+// Normalize rewrites the identifiers of p into canonical form, recording each
+// outcome. Identifiers which fail to parse are kept verbatim and returned as
+// errors.
+func (p *PaperIdJson) Normalize(outcomes *ids.Outcomes) []*FieldError {
+	var fieldErrs []*FieldError
+
+	for _, field := range []struct {
+		kind  ids.Kind
+		value *string
+	}{
+		{ids.KindDoi, &p.Doi},
+		{ids.KindArxiv, &p.Arxiv},
+		{ids.KindPmid, &p.Pmid},
+		{ids.KindPmcid, &p.Pmcid},
+		{ids.KindIstexId, &p.IstexId},
+	} {
+		normalized, err := outcomes.Normalize(field.kind, *field.value)
+		if err != nil {
+			fieldErrs = append(fieldErrs, &FieldError{string(field.kind), fmt.Errorf("%w: normalizing %s: %w", ErrParsePaperIdJson, field.kind, err)})
+			continue
+		}
+		*field.value = normalized
+	}
+
+	return fieldErrs
+}
+
 func (p *PaperIdJson) MarshalProto() (*PaperId, error) {
+	x, fieldErrs := p.MarshalProtoPartial()
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs[0].Err
+	}
+
+	return x, nil
+}
+
+// FieldError is a failure to convert a single field of a PaperIdJson.
+type FieldError struct {
+	// Field is the JSON name of the field which failed to convert.
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalProtoPartial converts p to a PaperId, leaving unset any field which
+// fails to convert and returning the failures in field order.
+// An invalid Id cannot be left unset, so in that case the PaperId is nil.
+func (p *PaperIdJson) MarshalProtoPartial() (*PaperId, []*FieldError) {
 	x := &PaperId{}
+	var fieldErrs []*FieldError
 	var err error
 	x.Id, err = ToUUID(p.Id)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing UUID: %w", ErrParsePaperIdJson, err)
+		return nil, []*FieldError{{"id", fmt.Errorf("%w: parsing UUID: %w", ErrParsePaperIdJson, err)}}
 	}
 
 	x.Doi = p.Doi
@@ -273,32 +299,33 @@ func (p *PaperIdJson) MarshalProto() (*PaperId, error) {
 
 	x.Pmid, err = ToPmid(p.Pmid)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing PMID: %w", ErrParsePaperIdJson, err)
+		fieldErrs = append(fieldErrs, &FieldError{"pmid", fmt.Errorf("%w: parsing PMID: %w", ErrParsePaperIdJson, err)})
 	}
 
 	x.Pmcid, err = ToPmcid(p.Pmcid)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing PMCID: %w", ErrParsePaperIdJson, err)
+		fieldErrs = append(fieldErrs, &FieldError{"pmcid", fmt.Errorf("%w: parsing PMCID: %w", ErrParsePaperIdJson, err)})
 	}
 
 	x.IstexId, err = ToIstexId(p.IstexId)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing IstexId: %w", ErrParsePaperIdJson, err)
+		fieldErrs = append(fieldErrs, &FieldError{"istexId", fmt.Errorf("%w: parsing IstexId: %w", ErrParsePaperIdJson, err)})
 	}
 
 	x.Resources, err = ToResources(p.Resources)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing resources: %w", ErrParsePaperIdJson, err)
+		x.Resources = nil
+		fieldErrs = append(fieldErrs, &FieldError{"resources", fmt.Errorf("%w: parsing resources: %w", ErrParsePaperIdJson, err)})
 	}
 
 	x.License, err = ToLicenseType(p.License)
 	if err != nil {
-		return nil, fmt.Errorf("%w: parsing license: %w", ErrParsePaperIdJson, err)
+		fieldErrs = append(fieldErrs, &FieldError{"license", fmt.Errorf("%w: parsing license: %w", ErrParsePaperIdJson, err)})
 	}
 
 	x.OaLink = p.OaLink
 
-	return x, nil
+	return x, fieldErrs
 }
 
 func (p *PaperIdJson) UnmarshalProto(x *PaperId) error {
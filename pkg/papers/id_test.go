@@ -0,0 +1,47 @@
+package papers
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
+	"testing"
+)
+
+func TestPaperIdJson_Normalize(t *testing.T) {
+	p := &PaperIdJson{
+		Doi:     "https://doi.org/10.1371/ABC",
+		Arxiv:   "not an arXiv id",
+		Pmid:    "PMID: 12345",
+		Pmcid:   "PMC0",
+		IstexId: "4B98",
+	}
+
+	outcomes := &ids.Outcomes{}
+	fieldErrs := p.Normalize(outcomes)
+
+	// Invalid identifiers are kept verbatim.
+	want := &PaperIdJson{
+		Doi:     "10.1371/abc",
+		Arxiv:   "not an arXiv id",
+		Pmid:    "12345",
+		Pmcid:   "PMC0",
+		IstexId: "4B98",
+	}
+	if diff := cmp.Diff(want, p); diff != "" {
+		t.Error(diff)
+	}
+
+	var gotFields []string
+	for _, fieldErr := range fieldErrs {
+		gotFields = append(gotFields, fieldErr.Field)
+	}
+	wantFields := []string{"arxiv", "pmcid", "istexId"}
+	if diff := cmp.Diff(wantFields, gotFields); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, kind := range []ids.Kind{ids.KindArxiv, ids.KindPmcid, ids.KindIstexId} {
+		if got := outcomes.Count(kind, ids.Invalid); got != 1 {
+			t.Errorf("got %d invalid %s, want 1", got, kind)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package ids parses and canonicalizes the external identifiers of papers:
+// DOIs, arXiv identifiers, PMIDs, PMCIDs, and ISTEX ids.
+//
+// Each Normalize function accepts the variants of an identifier found in the
+// SoftCite metadata and returns its canonical string form. Empty input
+// normalizes to the empty string without error.
+package ids
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid identifier")
+
+// Kind is the type of identifier.
+type Kind string
+
+const (
+	KindDoi     Kind = "doi"
+	KindArxiv   Kind = "arxiv"
+	KindPmid    Kind = "pmid"
+	KindPmcid   Kind = "pmcid"
+	KindIstexId Kind = "istexId"
+)
+
+// Kinds are all identifier kinds, in reporting order.
+var Kinds = []Kind{KindDoi, KindArxiv, KindPmid, KindPmcid, KindIstexId}
+
+// Normalize returns the canonical form of raw as an identifier of the given kind.
+func Normalize(kind Kind, raw string) (string, error) {
+	switch kind {
+	case KindDoi:
+		return NormalizeDoi(raw)
+	case KindArxiv:
+		return NormalizeArxiv(raw)
+	case KindPmid:
+		return NormalizePmid(raw)
+	case KindPmcid:
+		return NormalizePmcid(raw)
+	case KindIstexId:
+		return NormalizeIstexId(raw)
+	default:
+		return "", fmt.Errorf("%w: unknown identifier kind %q", ErrInvalid, kind)
+	}
+}
+
+// doiResolvers are the prefixes DOIs are sometimes written with.
+var doiResolvers = []string{
+	"https://doi.org/",
+	"http://doi.org/",
+	"https://dx.doi.org/",
+	"http://dx.doi.org/",
+	"doi.org/",
+	"doi:",
+}
+
+// doiPattern is a lower-cased DOI: the "10." directory indicator, a numeric
+// registrant code which may have dot-separated subdivisions, and a suffix.
+var doiPattern = regexp.MustCompile(`^10\.[0-9]+(\.[0-9]+)*/\S+$`)
+
+// NormalizeDoi strips any resolver prefix from a DOI and lower-cases it,
+// since DOIs are case-insensitive.
+//
+// Example: "https://doi.org/10.1371/journal.pone.0000001" becomes
+// "10.1371/journal.pone.0000001".
+func NormalizeDoi(raw string) (string, error) {
+	doi := strings.TrimSpace(raw)
+	if doi == "" {
+		return "", nil
+	}
+
+	doi = strings.ToLower(doi)
+	for _, resolver := range doiResolvers {
+		if strings.HasPrefix(doi, resolver) {
+			doi = strings.TrimSpace(doi[len(resolver):])
+			break
+		}
+	}
+
+	if !doiPattern.MatchString(doi) {
+		return "", fmt.Errorf("%w: DOI %q must begin with a \"10.\" registrant prefix", ErrInvalid, raw)
+	}
+
+	return doi, nil
+}
+
+// Arxiv is a parsed arXiv identifier.
+// See: https://info.arxiv.org/help/arxiv_identifier.html
+type Arxiv struct {
+	// Id is the unversioned identifier, either new-style "1501.00001" or
+	// old-style "hep-th/9901001".
+	Id string
+	// Version is the version of the article. Zero values are unversioned.
+	Version uint32
+}
+
+func (a Arxiv) String() string {
+	if a.Version == 0 {
+		return "arXiv:" + a.Id
+	}
+	return fmt.Sprintf("arXiv:%sv%d", a.Id, a.Version)
+}
+
+var (
+	// arxivNewPattern matches identifiers since April 2007: YYMM.NNNN, with a
+	// five-digit sequence number since January 2015.
+	arxivNewPattern = regexp.MustCompile(`^([0-9]{2}(0[1-9]|1[0-2])\.[0-9]{4,5})(v([0-9]+))?$`)
+	// arxivOldPattern matches identifiers before April 2007: archive, an
+	// optional subject class, then YYMMNNN.
+	arxivOldPattern = regexp.MustCompile(`^([a-z]+(-[a-z]+)*(\.[A-Z]{2})?/[0-9]{2}(0[1-9]|1[0-2])[0-9]{3})(v([0-9]+))?$`)
+)
+
+// ParseArxiv parses an arXiv identifier with or without an "arXiv:" prefix.
+func ParseArxiv(raw string) (Arxiv, error) {
+	id := strings.TrimSpace(raw)
+	if len(id) >= 6 && strings.EqualFold(id[:6], "arxiv:") {
+		id = id[6:]
+	}
+
+	var idPart, versionPart string
+	if m := arxivNewPattern.FindStringSubmatch(id); m != nil {
+		idPart, versionPart = m[1], m[4]
+	} else if m := arxivOldPattern.FindStringSubmatch(id); m != nil {
+		idPart, versionPart = m[1], m[6]
+	} else {
+		return Arxiv{}, fmt.Errorf("%w: arXiv identifier %q", ErrInvalid, raw)
+	}
+
+	result := Arxiv{Id: idPart}
+	if versionPart != "" {
+		version, err := strconv.ParseUint(versionPart, 10, 32)
+		if err != nil || version == 0 {
+			return Arxiv{}, fmt.Errorf("%w: arXiv identifier %q has invalid version", ErrInvalid, raw)
+		}
+		result.Version = uint32(version)
+	}
+
+	return result, nil
+}
+
+// NormalizeArxiv returns the canonical "arXiv:"-prefixed form of an arXiv
+// identifier.
+func NormalizeArxiv(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	arxiv, err := ParseArxiv(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return arxiv.String(), nil
+}
+
+// ParsePmid parses a PubMed ID. PMIDs are positive integers and are stored
+// as 32-bit unsigned integers.
+func ParsePmid(raw string) (uint32, error) {
+	id := strings.TrimSpace(raw)
+	if len(id) >= 5 && strings.EqualFold(id[:5], "pmid:") {
+		id = strings.TrimSpace(id[5:])
+	}
+
+	result, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: PMID %q: %w", ErrInvalid, raw, err)
+	}
+	if result == 0 {
+		return 0, fmt.Errorf("%w: PMID %q must be positive", ErrInvalid, raw)
+	}
+
+	return uint32(result), nil
+}
+
+func NormalizePmid(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	id, err := ParsePmid(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(uint64(id), 10), nil
+}
+
+// Pmcid is a parsed PubMed Central ID.
+type Pmcid struct {
+	Id uint32
+	// Version is the version of the article. Zero values are unversioned.
+	Version uint32
+}
+
+func (p Pmcid) String() string {
+	if p.Version == 0 {
+		return fmt.Sprintf("PMC%d", p.Id)
+	}
+	return fmt.Sprintf("PMC%d.%d", p.Id, p.Version)
+}
+
+// ParsePmcid parses a PMCID of the form "PMC${id}" or "PMC${id}.${version}".
+// The "PMC" prefix is case-insensitive and may be omitted.
+func ParsePmcid(raw string) (Pmcid, error) {
+	id := strings.TrimSpace(raw)
+	if len(id) >= 3 && strings.EqualFold(id[:3], "PMC") {
+		id = id[3:]
+	}
+
+	idPart, versionPart, versioned := strings.Cut(id, ".")
+
+	parsedId, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil {
+		return Pmcid{}, fmt.Errorf("%w: PMCID %q: %w", ErrInvalid, raw, err)
+	}
+	if parsedId == 0 {
+		return Pmcid{}, fmt.Errorf("%w: PMCID %q must be positive", ErrInvalid, raw)
+	}
+
+	result := Pmcid{Id: uint32(parsedId)}
+	if versioned {
+		version, err := strconv.ParseUint(versionPart, 10, 32)
+		if err != nil {
+			return Pmcid{}, fmt.Errorf("%w: PMCID %q version: %w", ErrInvalid, raw, err)
+		}
+		if version == 0 {
+			return Pmcid{}, fmt.Errorf("%w: PMCID %q versions begin at 1", ErrInvalid, raw)
+		}
+		result.Version = uint32(version)
+	}
+
+	return result, nil
+}
+
+func NormalizePmcid(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	id, err := ParsePmcid(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+// IstexIdLength is the number of hexadecimal characters in an ISTEX id.
+const IstexIdLength = 40
+
+// NormalizeIstexId validates an ISTEX id and returns it in capitalized
+// hexadecimal.
+func NormalizeIstexId(raw string) (string, error) {
+	id := strings.TrimSpace(raw)
+	if id == "" {
+		return "", nil
+	}
+
+	if len(id) != IstexIdLength {
+		return "", fmt.Errorf("%w: ISTEX id %q must be %d characters long, got %d", ErrInvalid, raw, IstexIdLength, len(id))
+	}
+
+	_, err := hex.DecodeString(id)
+	if err != nil {
+		return "", fmt.Errorf("%w: ISTEX id %q: %w", ErrInvalid, raw, err)
+	}
+
+	return strings.ToUpper(id), nil
+}
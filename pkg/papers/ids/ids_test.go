@@ -0,0 +1,136 @@
+package ids
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tcs := []struct {
+		name    string
+		kind    Kind
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "doi empty", kind: KindDoi, raw: "  ", want: ""},
+		{name: "doi bare", kind: KindDoi, raw: "10.1371/journal.pone.0000001", want: "10.1371/journal.pone.0000001"},
+		{name: "doi lower-cased", kind: KindDoi, raw: "10.1016/J.CELL.2009.01.042", want: "10.1016/j.cell.2009.01.042"},
+		{name: "doi https resolver", kind: KindDoi, raw: "https://doi.org/10.1371/ABC", want: "10.1371/abc"},
+		{name: "doi http resolver", kind: KindDoi, raw: "http://doi.org/10.1371/abc", want: "10.1371/abc"},
+		{name: "doi dx resolver", kind: KindDoi, raw: "https://dx.doi.org/10.1371/abc", want: "10.1371/abc"},
+		{name: "doi bare resolver", kind: KindDoi, raw: "doi.org/10.1371/abc", want: "10.1371/abc"},
+		{name: "doi prefix", kind: KindDoi, raw: "DOI: 10.1371/abc", want: "10.1371/abc"},
+		{name: "doi subdivided registrant", kind: KindDoi, raw: "10.1000.10/123", want: "10.1000.10/123"},
+		{name: "doi missing directory", kind: KindDoi, raw: "1371/abc", wantErr: true},
+		{name: "doi missing suffix", kind: KindDoi, raw: "10.1371/", wantErr: true},
+		{name: "doi whitespace in suffix", kind: KindDoi, raw: "10.1371/a b", wantErr: true},
+
+		{name: "arxiv new", kind: KindArxiv, raw: "1501.00001", want: "arXiv:1501.00001"},
+		{name: "arxiv new four digits", kind: KindArxiv, raw: "0704.0001", want: "arXiv:0704.0001"},
+		{name: "arxiv new versioned", kind: KindArxiv, raw: "arXiv:1501.00001v2", want: "arXiv:1501.00001v2"},
+		{name: "arxiv prefix case", kind: KindArxiv, raw: "ARXIV:1501.00001", want: "arXiv:1501.00001"},
+		{name: "arxiv old", kind: KindArxiv, raw: "hep-th/9901001", want: "arXiv:hep-th/9901001"},
+		{name: "arxiv old versioned", kind: KindArxiv, raw: "hep-th/9901001v3", want: "arXiv:hep-th/9901001v3"},
+		{name: "arxiv old subject class", kind: KindArxiv, raw: "math.GT/0309136", want: "arXiv:math.GT/0309136"},
+		{name: "arxiv month 13", kind: KindArxiv, raw: "1513.00001", wantErr: true},
+		{name: "arxiv version 0", kind: KindArxiv, raw: "1501.00001v0", wantErr: true},
+		{name: "arxiv six digit sequence", kind: KindArxiv, raw: "1501.000001", wantErr: true},
+
+		{name: "pmid", kind: KindPmid, raw: "12345", want: "12345"},
+		{name: "pmid prefix", kind: KindPmid, raw: "PMID: 12345", want: "12345"},
+		{name: "pmid lower-case prefix", kind: KindPmid, raw: "pmid:12345", want: "12345"},
+		{name: "pmid leading zeros", kind: KindPmid, raw: "007", want: "7"},
+		{name: "pmid zero", kind: KindPmid, raw: "0", wantErr: true},
+		{name: "pmid negative", kind: KindPmid, raw: "-1", wantErr: true},
+		{name: "pmid overflow", kind: KindPmid, raw: "4294967296", wantErr: true},
+		{name: "pmid text", kind: KindPmid, raw: "PMID", wantErr: true},
+
+		{name: "pmcid", kind: KindPmcid, raw: "PMC123", want: "PMC123"},
+		{name: "pmcid lower-case prefix", kind: KindPmcid, raw: "pmc123", want: "PMC123"},
+		{name: "pmcid no prefix", kind: KindPmcid, raw: "123", want: "PMC123"},
+		{name: "pmcid versioned", kind: KindPmcid, raw: "PMC123.2", want: "PMC123.2"},
+		{name: "pmcid zero", kind: KindPmcid, raw: "PMC0", wantErr: true},
+		{name: "pmcid version 0", kind: KindPmcid, raw: "PMC123.0", wantErr: true},
+		{name: "pmcid empty version", kind: KindPmcid, raw: "PMC123.", wantErr: true},
+
+		{name: "istex", kind: KindIstexId, raw: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A1", want: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A1"},
+		{name: "istex lower-case", kind: KindIstexId, raw: "4b98414e076fb3c1053ba36a5a2a7c2fa4ed35a1", want: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A1"},
+		{name: "istex short", kind: KindIstexId, raw: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A", wantErr: true},
+		{name: "istex long", kind: KindIstexId, raw: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35A1A", wantErr: true},
+		{name: "istex not hex", kind: KindIstexId, raw: "4B98414E076FB3C1053BA36A5A2A7C2FA4ED35AZ", wantErr: true},
+
+		{name: "unknown kind", kind: "isbn", raw: "978-3-16-148410-0", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Normalize(tc.kind, tc.raw)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("got error %v, want %v", err, ErrInvalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseArxiv(t *testing.T) {
+	tcs := []struct {
+		raw  string
+		want Arxiv
+	}{
+		{raw: "1501.00001", want: Arxiv{Id: "1501.00001"}},
+		{raw: "arXiv:1501.00001v12", want: Arxiv{Id: "1501.00001", Version: 12}},
+		{raw: "cond-mat/0501001v1", want: Arxiv{Id: "cond-mat/0501001", Version: 1}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.raw, func(t *testing.T) {
+			got, err := ParseArxiv(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParsePmcid(t *testing.T) {
+	got, err := ParsePmcid("PMC3531190.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Pmcid{Id: 3531190, Version: 1}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestOutcomes_Normalize(t *testing.T) {
+	outcomes := &Outcomes{}
+	for _, raw := range []string{"", "12345", "PMID:12345", "0"} {
+		_, _ = outcomes.Normalize(KindPmid, raw)
+	}
+
+	for outcome, want := range map[Outcome]int{
+		Empty:      1,
+		Unchanged:  1,
+		Normalized: 1,
+		Invalid:    1,
+	} {
+		if got := outcomes.Count(KindPmid, outcome); got != want {
+			t.Errorf("got %d %v outcomes, want %d", got, outcome, want)
+		}
+	}
+}
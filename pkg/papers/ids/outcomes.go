@@ -0,0 +1,112 @@
+package ids
+
+import (
+	"fmt"
+	"io"
+)
+
+// Outcome is the result of normalizing a single identifier.
+type Outcome int
+
+const (
+	// Empty identifiers were absent.
+	Empty Outcome = iota
+	// Unchanged identifiers were already in canonical form.
+	Unchanged
+	// Normalized identifiers were valid but rewritten to canonical form.
+	Normalized
+	// Invalid identifiers could not be parsed.
+	Invalid
+
+	nOutcomes
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Empty:
+		return "empty"
+	case Unchanged:
+		return "unchanged"
+	case Normalized:
+		return "normalized"
+	case Invalid:
+		return "invalid"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// Outcomes tallies normalization outcomes by identifier kind.
+// The zero value is ready to use. Outcomes is not safe for concurrent use;
+// give each worker its own and Merge them.
+type Outcomes struct {
+	counts map[Kind]*[nOutcomes]int
+}
+
+// Normalize normalizes raw as an identifier of the given kind and records
+// the outcome.
+func (o *Outcomes) Normalize(kind Kind, raw string) (string, error) {
+	normalized, err := Normalize(kind, raw)
+
+	switch {
+	case err != nil:
+		o.Add(kind, Invalid, 1)
+	case normalized == "":
+		o.Add(kind, Empty, 1)
+	case normalized == raw:
+		o.Add(kind, Unchanged, 1)
+	default:
+		o.Add(kind, Normalized, 1)
+	}
+
+	return normalized, err
+}
+
+func (o *Outcomes) Add(kind Kind, outcome Outcome, n int) {
+	if o.counts == nil {
+		o.counts = make(map[Kind]*[nOutcomes]int)
+	}
+
+	counts, found := o.counts[kind]
+	if !found {
+		counts = &[nOutcomes]int{}
+		o.counts[kind] = counts
+	}
+	counts[outcome] += n
+}
+
+// Count returns the number of identifiers of kind with the given outcome.
+func (o *Outcomes) Count(kind Kind, outcome Outcome) int {
+	counts, found := o.counts[kind]
+	if !found {
+		return 0
+	}
+	return counts[outcome]
+}
+
+func (o *Outcomes) Merge(other *Outcomes) {
+	for kind, counts := range other.counts {
+		for outcome, n := range counts {
+			o.Add(kind, Outcome(outcome), n)
+		}
+	}
+}
+
+// WriteTable writes the outcomes as semicolon-separated lines of kind,
+// outcome, and count. Kinds with no recorded outcomes are omitted.
+func (o *Outcomes) WriteTable(w io.Writer) error {
+	for _, kind := range Kinds {
+		if _, found := o.counts[kind]; !found {
+			continue
+		}
+
+		for outcome := range nOutcomes {
+			_, err := fmt.Fprintf(w, "%s;%s;%d\n", kind, outcome, o.Count(kind, outcome))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,451 @@
+package papers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/willbeason/software-mentions/pkg/papers/ids"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+)
+
+// IndexExt is the extension of sidecar index files, appended to the path of
+// the indexed .pbl file.
+const IndexExt = ".idx"
+
+// offsetWidth is the number of bytes used to store each record offset.
+const offsetWidth = 8
+
+// headerWidth is the number of bytes before the first entry of an index file.
+const headerWidth = 8
+
+var (
+	ErrIndex      = errors.New("indexing .pbl file")
+	ErrStaleIndex = errors.New("index is out of date with its .pbl file")
+)
+
+// Index describes a sidecar index over a .pbl file.
+//
+// An index file begins with a header of the big-endian size of the indexed .pbl
+// file, so indexes of a regenerated .pbl file are rejected rather than read at
+// stale offsets. The header is followed by fixed-width entries sorted by key.
+// Each entry is a key of KeyWidth bytes followed by the big-endian byte offset of the
+// matching record in the .pbl file. Numeric keys are big-endian so that byte
+// order and numeric order agree.
+type Index struct {
+	// Name distinguishes the sidecar file of a secondary index.
+	// The primary index has no name.
+	Name string
+
+	// KeyWidth is the length of every key in bytes.
+	KeyWidth int
+
+	// Key returns the key of entry, or false if entry has nothing to index.
+	Key func(entry *PaperId) ([]byte, bool)
+}
+
+var (
+	// UUIDIndex is the primary index, keyed by the raw bytes of PaperId.Id.
+	UUIDIndex = &Index{
+		KeyWidth: 16,
+		Key: func(entry *PaperId) ([]byte, bool) {
+			if entry.Id == nil || len(entry.Id.Id) != 16 {
+				return nil, false
+			}
+			return entry.Id.Id, true
+		},
+	}
+
+	// DoiIndex is keyed by a hash of the DOI, so lookups must compare the
+	// DOIs of matching records to rule out collisions.
+	DoiIndex = &Index{
+		Name:     "doi",
+		KeyWidth: 8,
+		Key: func(entry *PaperId) ([]byte, bool) {
+			if entry.Doi == "" {
+				return nil, false
+			}
+			return DoiKey(entry.Doi), true
+		},
+	}
+
+	PmidIndex = &Index{
+		Name:     "pmid",
+		KeyWidth: 4,
+		Key: func(entry *PaperId) ([]byte, bool) {
+			if entry.Pmid == nil {
+				return nil, false
+			}
+			return binary.BigEndian.AppendUint32(nil, entry.Pmid.Id), true
+		},
+	}
+
+	// PmcidIndex ignores PMCID versions.
+	PmcidIndex = &Index{
+		Name:     "pmcid",
+		KeyWidth: 4,
+		Key: func(entry *PaperId) ([]byte, bool) {
+			if entry.Pmcid == nil {
+				return nil, false
+			}
+			return binary.BigEndian.AppendUint32(nil, entry.Pmcid.Id), true
+		},
+	}
+)
+
+// SecondaryIndexes are the optional indexes, by name.
+var SecondaryIndexes = map[string]*Index{
+	DoiIndex.Name:   DoiIndex,
+	PmidIndex.Name:  PmidIndex,
+	PmcidIndex.Name: PmcidIndex,
+}
+
+// Path returns the path of the sidecar file of this index for the .pbl file
+// at pblPath.
+func (idx *Index) Path(pblPath string) string {
+	if idx.Name == "" {
+		return pblPath + IndexExt
+	}
+	return pblPath + "." + idx.Name + IndexExt
+}
+
+func (idx *Index) entryWidth() int {
+	return idx.KeyWidth + offsetWidth
+}
+
+// DoiKey is the DoiIndex key of doi. Equivalent spellings of a DOI share a key.
+func DoiKey(doi string) []byte {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(canonicalDoi(doi)))
+	return h.Sum(nil)
+}
+
+// canonicalDoi normalizes doi, falling back to lower case if it is invalid so
+// that invalid DOIs may still be looked up verbatim.
+func canonicalDoi(doi string) string {
+	normalized, err := ids.NormalizeDoi(doi)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(doi))
+	}
+	return normalized
+}
+
+// IndexBuilder accumulates index entries in memory while a .pbl file is read.
+type IndexBuilder struct {
+	indexes []*Index
+	entries []*indexEntries
+}
+
+func NewIndexBuilder(indexes ...*Index) *IndexBuilder {
+	b := &IndexBuilder{indexes: indexes}
+	for _, idx := range indexes {
+		b.entries = append(b.entries, &indexEntries{width: idx.entryWidth()})
+	}
+	return b
+}
+
+// Add records entry, which begins at offset in the .pbl file.
+func (b *IndexBuilder) Add(offset int64, entry *PaperId) {
+	for i, idx := range b.indexes {
+		key, ok := idx.Key(entry)
+		if !ok {
+			continue
+		}
+		e := b.entries[i]
+		e.data = append(e.data, key...)
+		e.data = binary.BigEndian.AppendUint64(e.data, uint64(offset))
+	}
+}
+
+// Write sorts the accumulated entries and writes one sidecar file per index
+// next to pblPath.
+func (b *IndexBuilder) Write(pblPath string) error {
+	stat, err := os.Stat(pblPath)
+	if err != nil {
+		return fmt.Errorf("%w: reading stats of %q: %w", ErrIndex, pblPath, err)
+	}
+	header := binary.BigEndian.AppendUint64(nil, uint64(stat.Size()))
+
+	for i, idx := range b.indexes {
+		entries := b.entries[i]
+		sort.Sort(entries)
+
+		err := writeIndexFile(idx.Path(pblPath), header, entries.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeIndexFile(path string, header, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w: creating %q: %w", ErrIndex, path, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	_, err = writer.Write(header)
+	if err == nil {
+		_, err = writer.Write(data)
+	}
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("%w: writing %q: %w", ErrIndex, path, err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("%w: flushing %q: %w", ErrIndex, path, err)
+	}
+
+	return file.Close()
+}
+
+// indexEntries sorts fixed-width entries by key, then by offset.
+type indexEntries struct {
+	data  []byte
+	width int
+	tmp   []byte
+}
+
+func (e *indexEntries) Len() int {
+	return len(e.data) / e.width
+}
+
+func (e *indexEntries) Less(i, j int) bool {
+	return bytes.Compare(e.entry(i), e.entry(j)) < 0
+}
+
+func (e *indexEntries) Swap(i, j int) {
+	if e.tmp == nil {
+		e.tmp = make([]byte, e.width)
+	}
+	copy(e.tmp, e.entry(i))
+	copy(e.entry(i), e.entry(j))
+	copy(e.entry(j), e.tmp)
+}
+
+func (e *indexEntries) entry(i int) []byte {
+	return e.data[i*e.width : (i+1)*e.width]
+}
+
+// PaperIndex provides random access to the records of a .pbl file through
+// its sidecar indexes.
+type PaperIndex struct {
+	pbl *os.File
+
+	indexes map[*Index]*os.File
+}
+
+// OpenPaperIndex opens the .pbl file at pblPath along with its primary index
+// and whichever secondary indexes exist.
+func OpenPaperIndex(pblPath string) (*PaperIndex, error) {
+	pbl, err := os.Open(pblPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %q: %w", ErrIndex, pblPath, err)
+	}
+
+	p := &PaperIndex{
+		pbl:     pbl,
+		indexes: make(map[*Index]*os.File),
+	}
+
+	stat, err := pbl.Stat()
+	if err != nil {
+		_ = p.Close()
+		return nil, fmt.Errorf("%w: reading stats of %q: %w", ErrIndex, pblPath, err)
+	}
+
+	primary, err := os.Open(UUIDIndex.Path(pblPath))
+	if err != nil {
+		_ = p.Close()
+		return nil, fmt.Errorf("%w: opening primary index: %w", ErrIndex, err)
+	}
+	p.indexes[UUIDIndex] = primary
+	err = checkHeader(primary, stat.Size())
+	if err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+
+	for _, idx := range SecondaryIndexes {
+		f, err := os.Open(idx.Path(pblPath))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			_ = p.Close()
+			return nil, fmt.Errorf("%w: opening %s index: %w", ErrIndex, idx.Name, err)
+		}
+		p.indexes[idx] = f
+		err = checkHeader(f, stat.Size())
+		if err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// checkHeader returns an error unless the index file f was written for a .pbl
+// file of pblSize bytes.
+func checkHeader(f *os.File, pblSize int64) error {
+	header := make([]byte, headerWidth)
+	_, err := f.ReadAt(header, 0)
+	if err != nil {
+		return fmt.Errorf("%w: reading header of %q: %w", ErrIndex, f.Name(), err)
+	}
+
+	indexedSize := int64(binary.BigEndian.Uint64(header))
+	if indexedSize != pblSize {
+		return fmt.Errorf("%w: %q indexes %d bytes but the .pbl file has %d, rebuild it with pbl-index",
+			ErrStaleIndex, f.Name(), indexedSize, pblSize)
+	}
+
+	return nil
+}
+
+func (p *PaperIndex) Close() error {
+	var errs []error
+	for _, f := range p.indexes {
+		errs = append(errs, f.Close())
+	}
+	errs = append(errs, p.pbl.Close())
+
+	return errors.Join(errs...)
+}
+
+// HasIndex returns whether the sidecar file for idx was found.
+func (p *PaperIndex) HasIndex(idx *Index) bool {
+	_, ok := p.indexes[idx]
+	return ok
+}
+
+// Lookup returns every record whose key under idx is key.
+func (p *PaperIndex) Lookup(idx *Index, key []byte) ([]*PaperId, error) {
+	offsets, err := p.offsets(idx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*PaperId, len(offsets))
+	for i, offset := range offsets {
+		result[i], err = ReadPblAt(p.pbl, offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// ByUUID returns the record for the paper with the given UUID, or nil if
+// there is none.
+func (p *PaperIndex) ByUUID(id string) (*PaperId, error) {
+	uuid, err := ToUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := p.Lookup(UUIDIndex, uuid.Id)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+
+	return found[0], nil
+}
+
+// ByDoi returns the records with the given DOI.
+func (p *PaperIndex) ByDoi(doi string) ([]*PaperId, error) {
+	found, err := p.Lookup(DoiIndex, DoiKey(doi))
+	if err != nil {
+		return nil, err
+	}
+
+	// Discard hash collisions.
+	var result []*PaperId
+	for _, entry := range found {
+		if canonicalDoi(entry.Doi) == canonicalDoi(doi) {
+			result = append(result, entry)
+		}
+	}
+
+	return result, nil
+}
+
+// ByPmid returns the records with the given PMID.
+func (p *PaperIndex) ByPmid(pmid string) ([]*PaperId, error) {
+	id, err := ToPmid(pmid)
+	if err != nil || id == nil {
+		return nil, err
+	}
+
+	return p.Lookup(PmidIndex, binary.BigEndian.AppendUint32(nil, id.Id))
+}
+
+// ByPmcid returns the records with the given PMCID, regardless of version.
+func (p *PaperIndex) ByPmcid(pmcid string) ([]*PaperId, error) {
+	id, err := ToPmcid(pmcid)
+	if err != nil || id == nil {
+		return nil, err
+	}
+
+	return p.Lookup(PmcidIndex, binary.BigEndian.AppendUint32(nil, id.Id))
+}
+
+// offsets binary searches the sidecar file of idx for entries matching key.
+func (p *PaperIndex) offsets(idx *Index, key []byte) ([]int64, error) {
+	f, ok := p.indexes[idx]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %q index", ErrIndex, idx.Name)
+	}
+	if len(key) != idx.KeyWidth {
+		return nil, fmt.Errorf("%w: got %d byte key for %q index but want %d", ErrIndex, len(key), idx.Name, idx.KeyWidth)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading stats of %q: %w", ErrIndex, f.Name(), err)
+	}
+	width := idx.entryWidth()
+	size := stat.Size() - headerWidth
+	if size < 0 || size%int64(width) != 0 {
+		return nil, fmt.Errorf("%w: %q is not a header and a whole number of %d byte entries", ErrIndex, f.Name(), width)
+	}
+	n := int(size / int64(width))
+
+	entry := make([]byte, width)
+	var readErr error
+	readEntry := func(i int) []byte {
+		_, err := f.ReadAt(entry, headerWidth+int64(i)*int64(width))
+		if err != nil && readErr == nil {
+			readErr = fmt.Errorf("%w: reading %q: %w", ErrIndex, f.Name(), err)
+		}
+		return entry
+	}
+
+	first := sort.Search(n, func(i int) bool {
+		return bytes.Compare(readEntry(i)[:idx.KeyWidth], key) >= 0
+	})
+
+	var result []int64
+	for i := first; i < n && readErr == nil; i++ {
+		e := readEntry(i)
+		if !bytes.Equal(e[:idx.KeyWidth], key) {
+			break
+		}
+		result = append(result, int64(binary.BigEndian.Uint64(e[idx.KeyWidth:])))
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	return result, nil
+}
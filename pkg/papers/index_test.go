@@ -0,0 +1,252 @@
+package papers
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustPaperId(t *testing.T, uuid, doi, pmid, pmcid string) *PaperId {
+	t.Helper()
+
+	id, err := ToUUID(uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ToPmid(pmid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := ToPmcid(pmcid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &PaperId{Id: id, Doi: doi, Pmid: p, Pmcid: pc}
+}
+
+// appendPbl appends entries to the .pbl file at path.
+func appendPbl(t *testing.T, path string, entries ...*PaperId) {
+	t.Helper()
+
+	var out []byte
+	for _, entry := range entries {
+		protoBytes, err := proto.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = binary.AppendUvarint(out, uint64(len(protoBytes)))
+		out = append(out, protoBytes...)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// buildIndexes indexes the .pbl file at path as pbl-index does, returning the
+// offset of each record.
+func buildIndexes(t *testing.T, path string, indexes ...*Index) []int64 {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	builder := NewIndexBuilder(indexes...)
+	reader := NewPblReader(f)
+	var offsets []int64
+	for {
+		offset := reader.Offset()
+		entry := &PaperId{}
+		err = reader.Read(entry)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		builder.Add(offset, entry)
+		offsets = append(offsets, offset)
+	}
+
+	err = builder.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return offsets
+}
+
+func TestPaperIndex(t *testing.T) {
+	a := mustPaperId(t, "4b98c4d2-0b8e-4a7f-9d3e-1f2a3b4c5d6e", "10.1000/ABC", "12345", "PMC678.2")
+	b := mustPaperId(t, "00000000-0000-4000-8000-000000000001", "10.1000/xyz", "", "")
+	// c shares a's PMID but nothing else.
+	c := mustPaperId(t, "00000000-0000-4000-8000-000000000002", "", "12345", "PMC999")
+
+	path := filepath.Join(t.TempDir(), "ids"+PblExt)
+	appendPbl(t, path, a, b, c)
+	buildIndexes(t, path, UUIDIndex, DoiIndex, PmidIndex, PmcidIndex)
+
+	index, err := OpenPaperIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+
+	for _, idx := range []*Index{UUIDIndex, DoiIndex, PmidIndex, PmcidIndex} {
+		if !index.HasIndex(idx) {
+			t.Errorf("missing %q index", idx.Name)
+		}
+	}
+
+	gotUUID, err := index.ByUUID("00000000-0000-4000-8000-000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(b, gotUUID, protocmp.Transform()); diff != "" {
+		t.Error(diff)
+	}
+
+	gotMissing, err := index.ByUUID("00000000-0000-4000-8000-000000000003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotMissing != nil {
+		t.Errorf("got %v for a UUID not in the index, want nil", gotMissing)
+	}
+
+	tcs := []struct {
+		name   string
+		lookup func() ([]*PaperId, error)
+		want   []*PaperId
+	}{
+		{
+			name:   "doi",
+			lookup: func() ([]*PaperId, error) { return index.ByDoi("https://doi.org/10.1000/abc") },
+			want:   []*PaperId{a},
+		},
+		{
+			name:   "missing doi",
+			lookup: func() ([]*PaperId, error) { return index.ByDoi("10.1000/none") },
+			want:   nil,
+		},
+		{
+			name:   "pmid",
+			lookup: func() ([]*PaperId, error) { return index.ByPmid("12345") },
+			want:   []*PaperId{a, c},
+		},
+		{
+			// PMCID versions are ignored.
+			name:   "pmcid",
+			lookup: func() ([]*PaperId, error) { return index.ByPmcid("PMC678") },
+			want:   []*PaperId{a},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.lookup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestPaperIndex_ByDoiCollision(t *testing.T) {
+	a := mustPaperId(t, "00000000-0000-4000-8000-000000000001", "10.1000/a", "", "")
+	b := mustPaperId(t, "00000000-0000-4000-8000-000000000002", "10.1000/b", "", "")
+
+	path := filepath.Join(t.TempDir(), "ids"+PblExt)
+	appendPbl(t, path, a, b)
+	offsets := buildIndexes(t, path, UUIDIndex)
+
+	// Index b under the key of a, as if the DOIs' hashes collided.
+	builder := NewIndexBuilder(DoiIndex)
+	builder.Add(offsets[0], a)
+	builder.Add(offsets[1], a)
+	err := builder.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := OpenPaperIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = index.Close()
+	}()
+
+	found, err := index.Lookup(DoiIndex, DoiKey("10.1000/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*PaperId{a, b}, found, protocmp.Transform()); diff != "" {
+		t.Error(diff)
+	}
+
+	got, err := index.ByDoi("10.1000/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*PaperId{a}, got, protocmp.Transform()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOpenPaperIndex_Stale(t *testing.T) {
+	a := mustPaperId(t, "00000000-0000-4000-8000-000000000001", "10.1000/a", "", "")
+	b := mustPaperId(t, "00000000-0000-4000-8000-000000000002", "10.1000/b", "", "")
+
+	tcs := []struct {
+		name string
+		// rebuilt are the indexes rebuilt after appending to the .pbl file.
+		rebuilt []*Index
+	}{
+		{name: "primary", rebuilt: nil},
+		{name: "secondary", rebuilt: []*Index{UUIDIndex}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ids"+PblExt)
+			appendPbl(t, path, a)
+			buildIndexes(t, path, UUIDIndex, DoiIndex)
+
+			appendPbl(t, path, b)
+			if len(tc.rebuilt) > 0 {
+				buildIndexes(t, path, tc.rebuilt...)
+			}
+
+			_, err := OpenPaperIndex(path)
+			if !errors.Is(err, ErrStaleIndex) {
+				t.Errorf("got error %v, want %v", err, ErrStaleIndex)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package papers
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// UnknownLicense is the canonical id of licenses missing from the mapping.
+const UnknownLicense = "LicenseRef-unknown"
+
+// defaultLicenses maps the license strings found in the SoftCite metadata.
+// Creative Commons licenses are unversioned in the metadata and SPDX ids always
+// include the version, so they use LicenseRef- ids such as LicenseRef-cc-by, as
+// do other licenses without an SPDX id.
+//
+//go:embed licenses.csv
+var defaultLicenses []byte
+
+var ErrLicenseMapping = errors.New("reading license mapping")
+
+// License is the canonical form of a license string.
+type License struct {
+	// Spdx is the SPDX license identifier, or a LicenseRef- identifier for
+	// licenses SPDX does not list.
+	Spdx string
+	Type LicenseType
+}
+
+// LicenseMapping maps raw license strings to canonical licenses.
+// Strings are matched exactly first, then ignoring case, spacing, and
+// hyphenation, so "CC BY" and "cc-by" match the same entry.
+type LicenseMapping struct {
+	exact map[string]License
+	fuzzy map[string]License
+}
+
+// NewLicenseMapping returns a mapping of the default licenses.
+func NewLicenseMapping() *LicenseMapping {
+	m := &LicenseMapping{
+		exact: make(map[string]License),
+		fuzzy: make(map[string]License),
+	}
+
+	err := m.Load(bytes.NewReader(defaultLicenses))
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// LoadLicenseMapping returns the default mapping overridden by the entries in
+// the CSV file at path, if path is not empty.
+func LoadLicenseMapping(path string) (*LicenseMapping, error) {
+	m := NewLicenseMapping()
+	if path == "" {
+		return m, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %q: %w", ErrLicenseMapping, path, err)
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	err = m.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrLicenseMapping, path, err)
+	}
+
+	return m, nil
+}
+
+// Load adds the entries of a CSV with the header "raw,spdx,license_type",
+// replacing existing entries for the same raw strings. license_type is the
+// name of a LicenseType, such as "LICENSE_CC_BY".
+func (m *LicenseMapping) Load(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading header: %w", ErrLicenseMapping, err)
+	}
+	if header[0] != "raw" || header[1] != "spdx" || header[2] != "license_type" {
+		return fmt.Errorf("%w: got header %v but want [raw spdx license_type]", ErrLicenseMapping, header)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %w", ErrLicenseMapping, err)
+		}
+
+		licenseType, found := LicenseType_value[record[2]]
+		if !found {
+			return fmt.Errorf("%w: unknown license type %q for %q", ErrLicenseMapping, record[2], record[0])
+		}
+		if record[1] == "" {
+			return fmt.Errorf("%w: missing spdx id for %q", ErrLicenseMapping, record[0])
+		}
+
+		license := License{Spdx: record[1], Type: LicenseType(licenseType)}
+		m.exact[record[0]] = license
+		m.fuzzy[licenseKey(record[0])] = license
+	}
+
+	return nil
+}
+
+// Lookup returns the canonical license for raw, or false if it is unmapped.
+func (m *LicenseMapping) Lookup(raw string) (License, bool) {
+	if license, found := m.exact[raw]; found {
+		return license, true
+	}
+
+	license, found := m.fuzzy[licenseKey(raw)]
+	return license, found
+}
+
+// licenseKey lower-cases s and collapses runs of spaces, hyphens, and
+// underscores into single hyphens.
+func licenseKey(s string) string {
+	var sb strings.Builder
+	separator := false
+	for _, r := range strings.TrimSpace(s) {
+		if unicode.IsSpace(r) || r == '-' || r == '_' {
+			separator = true
+			continue
+		}
+		if separator && sb.Len() > 0 {
+			sb.WriteRune('-')
+		}
+		separator = false
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+var defaultLicenseMapping = sync.OnceValue(NewLicenseMapping)
@@ -0,0 +1,146 @@
+package papers
+
+import (
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLicenseKey(t *testing.T) {
+	tcs := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "cc-by", want: "cc-by"},
+		{raw: "CC BY", want: "cc-by"},
+		{raw: " CC__By-NC  ", want: "cc-by-nc"},
+		{raw: "-cc by-", want: "cc-by"},
+		{raw: "Open Government Licence - Canada", want: "open-government-licence-canada"},
+		{raw: "", want: ""},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.raw, func(t *testing.T) {
+			if got := licenseKey(tc.raw); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLicenseMapping_Lookup(t *testing.T) {
+	tcs := []struct {
+		raw       string
+		want      License
+		wantFound bool
+	}{
+		{
+			raw:       "cc-by",
+			want:      License{Spdx: "LicenseRef-cc-by", Type: LicenseType_LICENSE_CC_BY},
+			wantFound: true,
+		},
+		{
+			// Matched ignoring case, spacing, and hyphenation.
+			raw:       " Cc_By-nc ",
+			want:      License{Spdx: "LicenseRef-cc-by-nc", Type: LicenseType_LICENSE_CC_BY_NC},
+			wantFound: true,
+		},
+		{
+			raw:       "Open Government Licence - Canada",
+			want:      License{Spdx: "LicenseRef-ogl-canada", Type: LicenseType_LICENSE_OPEN_GOVERNMENT_LICENSE_CANADA},
+			wantFound: true,
+		},
+		{
+			raw:       "cc-by-4.0",
+			wantFound: false,
+		},
+	}
+
+	m := NewLicenseMapping()
+	for _, tc := range tcs {
+		t.Run(tc.raw, func(t *testing.T) {
+			got, found := m.Lookup(tc.raw)
+			if found != tc.wantFound {
+				t.Fatalf("got found %t, want %t", found, tc.wantFound)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLoadLicenseMapping_Overrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licenses.csv")
+	err := os.WriteFile(path, []byte(strings.Join([]string{
+		"raw,spdx,license_type",
+		"CC BY,CC-BY-4.0,LICENSE_CC_BY",
+		"house license,LicenseRef-house,LICENSE_PUBLISHER_SPECIFIC_LICENSE",
+	}, "\n")), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadLicenseMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		raw  string
+		want string
+	}{
+		// The override replaces the default entry for the same string.
+		{raw: "CC BY", want: "CC-BY-4.0"},
+		// Exact matches of default entries take precedence over fuzzy matches
+		// of overrides.
+		{raw: "cc-by", want: "LicenseRef-cc-by"},
+		// Other spellings fuzzily match the override, which was loaded last.
+		{raw: "cc by", want: "CC-BY-4.0"},
+		{raw: "House-License", want: "LicenseRef-house"},
+		// Unrelated defaults are kept.
+		{raw: "cc0", want: "CC0-1.0"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.raw, func(t *testing.T) {
+			got, found := m.Lookup(tc.raw)
+			if !found {
+				t.Fatalf("%q is unmapped", tc.raw)
+			}
+			if got.Spdx != tc.want {
+				t.Errorf("got %q, want %q", got.Spdx, tc.want)
+			}
+		})
+	}
+
+	// The default mapping is unchanged.
+	got, _ := NewLicenseMapping().Lookup("CC BY")
+	if got.Spdx != "LicenseRef-cc-by" {
+		t.Errorf("got %q after loading overrides, want %q", got.Spdx, "LicenseRef-cc-by")
+	}
+}
+
+func TestLicenseMapping_Load_Invalid(t *testing.T) {
+	tcs := []struct {
+		name string
+		csv  string
+	}{
+		{name: "header", csv: "raw,spdx,type\ncc-by,LicenseRef-cc-by,LICENSE_CC_BY\n"},
+		{name: "license type", csv: "raw,spdx,license_type\ncc-by,LicenseRef-cc-by,LICENSE_CC_BY_4\n"},
+		{name: "spdx", csv: "raw,spdx,license_type\ncc-by,,LICENSE_CC_BY\n"},
+		{name: "fields", csv: "raw,spdx,license_type\ncc-by,LicenseRef-cc-by\n"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewLicenseMapping().Load(strings.NewReader(tc.csv))
+			if !errors.Is(err, ErrLicenseMapping) {
+				t.Errorf("got error %v, want %v", err, ErrLicenseMapping)
+			}
+		})
+	}
+}
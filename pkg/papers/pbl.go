@@ -0,0 +1,104 @@
+package papers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+)
+
+// PblExt is the file extension of a stream of PaperId protos, each preceded by
+// its length in bytes as a Uvarint.
+const PblExt = ".pbl"
+
+var ErrReadPbl = errors.New("reading .pbl stream")
+
+// PblReader sequentially reads PaperIds from a .pbl stream, keeping track of
+// the byte offset of each record.
+type PblReader struct {
+	r *bufio.Reader
+
+	offset int64
+	buf    []byte
+}
+
+func NewPblReader(r io.Reader) *PblReader {
+	return &PblReader{
+		r:   bufio.NewReader(r),
+		buf: make([]byte, 0, 1<<8),
+	}
+}
+
+// Offset is the byte offset of the next record to be read.
+func (r *PblReader) Offset() int64 {
+	return r.offset
+}
+
+// Read decodes the next record into entry, which is reset first.
+// Returns io.EOF once the stream is exhausted.
+func (r *PblReader) Read(entry *PaperId) error {
+	nProtoBytes, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return fmt.Errorf("%w: reading proto size at offset %d: %w", ErrReadPbl, r.offset, err)
+	}
+
+	if int(nProtoBytes) > cap(r.buf) {
+		r.buf = make([]byte, nProtoBytes)
+	}
+	entryBytes := r.buf[:nProtoBytes]
+	_, err = io.ReadFull(r.r, entryBytes)
+	if err != nil {
+		return fmt.Errorf("%w: reading proto at offset %d: %w", ErrReadPbl, r.offset, err)
+	}
+
+	err = proto.Unmarshal(entryBytes, entry)
+	if err != nil {
+		return fmt.Errorf("%w: unmarshalling proto at offset %d: %w", ErrReadPbl, r.offset, err)
+	}
+
+	r.offset += int64(uvarintLen(nProtoBytes)) + int64(nProtoBytes)
+
+	return nil
+}
+
+// ReadPblAt decodes the record beginning at offset in r.
+func ReadPblAt(r io.ReaderAt, offset int64) (*PaperId, error) {
+	header := make([]byte, binary.MaxVarintLen64)
+	n, err := r.ReadAt(header, offset)
+	if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return nil, fmt.Errorf("%w: reading proto size at offset %d: %w", ErrReadPbl, offset, err)
+	}
+
+	nProtoBytes, nSizeBytes := binary.Uvarint(header[:n])
+	if nSizeBytes <= 0 {
+		return nil, fmt.Errorf("%w: invalid proto size at offset %d", ErrReadPbl, offset)
+	}
+
+	entryBytes := make([]byte, nProtoBytes)
+	_, err = r.ReadAt(entryBytes, offset+int64(nSizeBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading proto at offset %d: %w", ErrReadPbl, offset, err)
+	}
+
+	entry := &PaperId{}
+	err = proto.Unmarshal(entryBytes, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unmarshalling proto at offset %d: %w", ErrReadPbl, offset, err)
+	}
+
+	return entry, nil
+}
+
+func uvarintLen(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
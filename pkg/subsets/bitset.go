@@ -0,0 +1,35 @@
+package subsets
+
+import "math/bits"
+
+// Bitset is a set of uint32s, such as paper ids, with one bit per value up to
+// the largest value added. Paper ids are dense, so this is far smaller than a
+// map of the same ids. The zero value is an empty set.
+type Bitset struct {
+	words []uint64
+}
+
+func (b *Bitset) Add(x uint32) {
+	word := int(x / 64)
+	if word >= len(b.words) {
+		b.words = append(b.words, make([]uint64, word+1-len(b.words))...)
+	}
+	b.words[word] |= 1 << (x % 64)
+}
+
+func (b *Bitset) Contains(x uint32) bool {
+	word := int(x / 64)
+	if word >= len(b.words) {
+		return false
+	}
+	return b.words[word]&(1<<(x%64)) != 0
+}
+
+// Len returns the number of values in the set.
+func (b *Bitset) Len() int {
+	n := 0
+	for _, word := range b.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
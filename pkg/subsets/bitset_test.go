@@ -0,0 +1,34 @@
+package subsets
+
+import (
+	"testing"
+)
+
+func TestBitset(t *testing.T) {
+	var b Bitset
+	if b.Contains(0) || b.Len() != 0 {
+		t.Fatal("zero Bitset is not empty")
+	}
+
+	values := []uint32{0, 1, 63, 64, 1000, 63}
+	for _, x := range values {
+		b.Add(x)
+	}
+
+	for _, x := range values {
+		if !b.Contains(x) {
+			t.Errorf("missing %d", x)
+		}
+	}
+	// Values between words, past the last word, and past the largest value.
+	for _, x := range []uint32{2, 62, 65, 999, 1001, 1 << 20, 1<<32 - 1} {
+		if b.Contains(x) {
+			t.Errorf("unexpected %d", x)
+		}
+	}
+
+	// 63 was added twice.
+	if got, want := b.Len(), 5; got != want {
+		t.Errorf("got Len() = %d, want %d", got, want)
+	}
+}
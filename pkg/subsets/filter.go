@@ -0,0 +1,481 @@
+package subsets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrFilter = errors.New("parsing filter")
+
+// Op is a comparison operator.
+type Op string
+
+const (
+	OpEq Op = "=="
+	OpNe Op = "!="
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+	OpIn Op = "in"
+)
+
+// Value is a literal in a filter: a string, number, or boolean.
+type Value struct {
+	Text   string
+	Number float64
+	Bool   bool
+	Kind   ValueKind
+}
+
+type ValueKind int
+
+const (
+	StringValue ValueKind = iota
+	NumberValue
+	BoolValue
+)
+
+// Comparison compares a column of a table to literal values.
+// OpIn matches if the column equals any of the values; the other operators
+// compare against the single value.
+type Comparison struct {
+	Table  string
+	Column string
+	Op     Op
+	Values []Value
+}
+
+// Filter is a conjunction of comparisons, such as
+//
+//	papers.published_year >= 2015 && mentions.software_normalized in ("R", "Python")
+//
+// Strings compare lexicographically, so dates compare as "2006-01-02" strings.
+// A comparison against a null value is false.
+type Filter []Comparison
+
+// Predicate returns the comparisons of the filter on the named table.
+func (f Filter) Predicate(table string) Predicate {
+	var result Predicate
+	for _, comparison := range f {
+		if comparison.Table == table {
+			result = append(result, comparison)
+		}
+	}
+	return result
+}
+
+// Tables returns the tables the filter compares, in order of first use.
+func (f Filter) Tables() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, comparison := range f {
+		if !seen[comparison.Table] {
+			seen[comparison.Table] = true
+			result = append(result, comparison.Table)
+		}
+	}
+	return result
+}
+
+// Predicate is a conjunction of comparisons on a single table.
+type Predicate []Comparison
+
+// Columns returns the columns the predicate reads.
+func (p Predicate) Columns() []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, comparison := range p {
+		if !seen[comparison.Column] {
+			seen[comparison.Column] = true
+			result = append(result, comparison.Column)
+		}
+	}
+	return result
+}
+
+// Bind returns a function reporting whether row i of record matches p.
+func (p Predicate) Bind(record arrow.Record) (func(i int) bool, error) {
+	matchers := make([]func(i int) bool, len(p))
+	for j, comparison := range p {
+		indices := record.Schema().FieldIndices(comparison.Column)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("%w: %s has no column %q", ErrFilter, comparison.Table, comparison.Column)
+		}
+
+		var err error
+		matchers[j], err = comparison.bind(record.Column(indices[0]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(i int) bool {
+		for _, matcher := range matchers {
+			if !matcher(i) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// MatchingMentions returns the software_mention_id of each row of the Parquet
+// file at inPath which matches p, such as the purpose assessments of a purpose.
+func MatchingMentions(ctx context.Context, inPath string, p Predicate) (map[string]struct{}, error) {
+	result := make(map[string]struct{})
+	columns := append([]string{tables.SoftwareMentionIdFieldName}, p.Columns()...)
+	err := Scan(ctx, inPath, columns, func(record arrow.Record) error {
+		idColumn, err := Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		match, err := p.Bind(record)
+		if err != nil {
+			return err
+		}
+
+		for i := range idColumn.Len() {
+			if match(i) {
+				result[idColumn.Value(i)] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PurposePredicate returns the purpose assessments at scope of one of purposes
+// with a certainty_score above minCertainty, as in the usual
+// certainty_score > 0.5.
+func PurposePredicate(scope string, purposes []string, minCertainty float64) (Predicate, error) {
+	switch scope {
+	case "document", "local":
+	default:
+		return nil, fmt.Errorf("%w: unknown scope %q", ErrFilter, scope)
+	}
+
+	purposeValues := make([]Value, len(purposes))
+	for i, purpose := range purposes {
+		switch purpose {
+		case "used", "created", "shared":
+		default:
+			return nil, fmt.Errorf("%w: unknown purpose %q", ErrFilter, purpose)
+		}
+		purposeValues[i] = Value{Text: purpose, Kind: StringValue}
+	}
+
+	return Predicate{
+		{Table: tables.PurposeAssessmentsName, Column: tables.ScopeFieldName, Op: OpEq,
+			Values: []Value{{Text: scope, Kind: StringValue}}},
+		{Table: tables.PurposeAssessmentsName, Column: tables.PurposeFieldName, Op: OpIn,
+			Values: purposeValues},
+		{Table: tables.PurposeAssessmentsName, Column: tables.CertaintyScoreFieldName, Op: OpGt,
+			Values: []Value{{Number: minCertainty, Kind: NumberValue}}},
+	}, nil
+}
+
+func (c Comparison) bind(column arrow.Array) (func(i int) bool, error) {
+	kind := StringValue
+	switch column.(type) {
+	case *array.Int8, *array.Int16, *array.Int32, *array.Int64,
+		*array.Uint8, *array.Uint16, *array.Uint32, *array.Uint64,
+		*array.Float32, *array.Float64:
+		kind = NumberValue
+	case *array.Boolean:
+		kind = BoolValue
+		if c.Op != OpEq && c.Op != OpNe && c.Op != OpIn {
+			return nil, fmt.Errorf("%w: cannot order boolean column %s.%s", ErrFilter, c.Table, c.Column)
+		}
+	}
+
+	for _, value := range c.Values {
+		if value.Kind != kind {
+			return nil, fmt.Errorf("%w: cannot compare %s column %s.%s to %s", ErrFilter, column.DataType(), c.Table, c.Column, value)
+		}
+	}
+
+	return func(i int) bool {
+		if column.IsNull(i) {
+			return false
+		}
+
+		var cmp func(value Value) int
+		switch kind {
+		case NumberValue:
+			x := numberValue(column, i)
+			cmp = func(value Value) int { return compareOrdered(x, value.Number) }
+		case BoolValue:
+			x := column.(*array.Boolean).Value(i)
+			cmp = func(value Value) int {
+				if x == value.Bool {
+					return 0
+				}
+				return 1
+			}
+		default:
+			x := column.ValueStr(i)
+			cmp = func(value Value) int { return strings.Compare(x, value.Text) }
+		}
+
+		if c.Op == OpIn {
+			for _, value := range c.Values {
+				if cmp(value) == 0 {
+					return true
+				}
+			}
+			return false
+		}
+
+		result := cmp(c.Values[0])
+		switch c.Op {
+		case OpEq:
+			return result == 0
+		case OpNe:
+			return result != 0
+		case OpLt:
+			return result < 0
+		case OpLe:
+			return result <= 0
+		case OpGt:
+			return result > 0
+		case OpGe:
+			return result >= 0
+		default:
+			panic("invalid op " + c.Op)
+		}
+	}, nil
+}
+
+func numberValue(column arrow.Array, i int) float64 {
+	switch c := column.(type) {
+	case *array.Int8:
+		return float64(c.Value(i))
+	case *array.Int16:
+		return float64(c.Value(i))
+	case *array.Int32:
+		return float64(c.Value(i))
+	case *array.Int64:
+		return float64(c.Value(i))
+	case *array.Uint8:
+		return float64(c.Value(i))
+	case *array.Uint16:
+		return float64(c.Value(i))
+	case *array.Uint32:
+		return float64(c.Value(i))
+	case *array.Uint64:
+		return float64(c.Value(i))
+	case *array.Float32:
+		return float64(c.Value(i))
+	case *array.Float64:
+		return c.Value(i)
+	default:
+		panic(fmt.Sprintf("not a number column: %T", column))
+	}
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v Value) String() string {
+	switch v.Kind {
+	case NumberValue:
+		return strconv.FormatFloat(v.Number, 'g', -1, 64)
+	case BoolValue:
+		return strconv.FormatBool(v.Bool)
+	default:
+		return strconv.Quote(v.Text)
+	}
+}
+
+// ParseFilter parses comparisons of the form TABLE.COLUMN OP VALUE, or
+// TABLE.COLUMN in (VALUE, ...), joined by "&&" or "and".
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var result Filter
+	for {
+		comparison, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, comparison)
+
+		if p.done() {
+			return result, nil
+		}
+		if next := p.next(); next != "&&" && next != "and" {
+			return nil, fmt.Errorf("%w: expected && but got %q", ErrFilter, next)
+		}
+	}
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) next() string {
+	if p.done() {
+		return ""
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+func (p *parser) comparison() (Comparison, error) {
+	var result Comparison
+
+	result.Table = p.next()
+	if !isIdentifier(result.Table) {
+		return result, fmt.Errorf("%w: expected table name but got %q", ErrFilter, result.Table)
+	}
+	if next := p.next(); next != "." {
+		return result, fmt.Errorf("%w: expected . after %q but got %q", ErrFilter, result.Table, next)
+	}
+	result.Column = p.next()
+	if !isIdentifier(result.Column) {
+		return result, fmt.Errorf("%w: expected column name but got %q", ErrFilter, result.Column)
+	}
+
+	op := Op(p.next())
+	switch op {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		value, err := p.value()
+		if err != nil {
+			return result, err
+		}
+		result.Values = []Value{value}
+	case OpIn:
+		if next := p.next(); next != "(" {
+			return result, fmt.Errorf("%w: expected ( after in but got %q", ErrFilter, next)
+		}
+		for {
+			value, err := p.value()
+			if err != nil {
+				return result, err
+			}
+			result.Values = append(result.Values, value)
+
+			next := p.next()
+			if next == ")" {
+				break
+			} else if next != "," {
+				return result, fmt.Errorf("%w: expected , or ) but got %q", ErrFilter, next)
+			}
+		}
+	default:
+		return result, fmt.Errorf("%w: unknown operator %q", ErrFilter, op)
+	}
+	result.Op = op
+
+	return result, nil
+}
+
+func (p *parser) value() (Value, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return Value{}, fmt.Errorf("%w: unexpected end of filter", ErrFilter)
+	case strings.HasPrefix(token, `"`):
+		s, err := strconv.Unquote(token)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: invalid string %s: %w", ErrFilter, token, err)
+		}
+		return Value{Text: s, Kind: StringValue}, nil
+	case token == "true" || token == "false":
+		return Value{Bool: token == "true", Kind: BoolValue}, nil
+	default:
+		x, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: expected value but got %q", ErrFilter, token)
+		}
+		return Value{Number: x, Kind: NumberValue}, nil
+	}
+}
+
+func isIdentifier(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i, r := range token {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits s into identifiers, numbers, quoted strings, and operators.
+func tokenize(s string) ([]string, error) {
+	var result []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string at %d", ErrFilter, i)
+			}
+			result = append(result, s[i:j+1])
+			i = j + 1
+		case strings.HasPrefix(s[i:], "&&"), strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			result = append(result, s[i:i+2])
+			i += 2
+		case strings.IndexByte(".(),<>", c) >= 0:
+			result = append(result, s[i:i+1])
+			i++
+		case c == '-' || c == '+' || c >= '0' && c <= '9':
+			j := i + 1
+			for ; j < len(s) && (strings.IndexByte("0123456789.eE+-", s[j]) >= 0); j++ {
+			}
+			result = append(result, s[i:j])
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for ; j < len(s) && (s[j] == '_' || s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || s[j] >= '0' && s[j] <= '9'); j++ {
+			}
+			result = append(result, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrFilter, c, i)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,237 @@
+package subsets
+
+import (
+	"errors"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tcs := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{name: "empty", s: "", want: nil},
+		{name: "comparison", s: "papers.published_year>=2015",
+			want: []string{"papers", ".", "published_year", ">=", "2015"}},
+		{name: "whitespace", s: " papers . genre\t==\n\"x\" ",
+			want: []string{"papers", ".", "genre", "==", `"x"`}},
+		{name: "two character operators", s: "&& == != <= >= < >",
+			want: []string{"&&", "==", "!=", "<=", ">=", "<", ">"}},
+		{name: "in list", s: `m.s in ("R", "Python")`,
+			want: []string{"m", ".", "s", "in", "(", `"R"`, ",", `"Python"`, ")"}},
+		{name: "escaped quote", s: `"a\"b"`, want: []string{`"a\"b"`}},
+		{name: "numbers", s: "-1 +2.5 1e-3", want: []string{"-1", "+2.5", "1e-3"}},
+		{name: "identifier with digits", s: "x_2", want: []string{"x_2"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tokenize(tc.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	for _, s := range []string{
+		`papers.title == "unterminated`,
+		`papers.title == "ends in escape\"`,
+		"papers.published_year = 2015",
+		"papers.published_year ! 2015",
+		"papers.title == 'single'",
+		"papers.title | 1",
+	} {
+		t.Run(s, func(t *testing.T) {
+			_, err := tokenize(s)
+			if !errors.Is(err, ErrFilter) {
+				t.Errorf("got error %v, want %v", err, ErrFilter)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tcs := []struct {
+		name string
+		s    string
+		want Filter
+	}{
+		{
+			name: "number",
+			s:    "papers.published_year >= 2015",
+			want: Filter{{Table: "papers", Column: "published_year", Op: OpGe,
+				Values: []Value{{Number: 2015, Kind: NumberValue}}}},
+		},
+		{
+			name: "string",
+			s:    `papers.genre != "journal-article"`,
+			want: Filter{{Table: "papers", Column: "genre", Op: OpNe,
+				Values: []Value{{Text: "journal-article", Kind: StringValue}}}},
+		},
+		{
+			name: "bool",
+			s:    "papers.has_mentions == true",
+			want: Filter{{Table: "papers", Column: "has_mentions", Op: OpEq,
+				Values: []Value{{Bool: true, Kind: BoolValue}}}},
+		},
+		{
+			name: "in",
+			s:    `mentions.software_normalized in ("R", "Python")`,
+			want: Filter{{Table: "mentions", Column: "software_normalized", Op: OpIn,
+				Values: []Value{{Text: "R", Kind: StringValue}, {Text: "Python", Kind: StringValue}}}},
+		},
+		{
+			name: "conjunction",
+			s:    `papers.published_year < 2020 && papers.published_year > 2010 and mentions.software_normalized == "R"`,
+			want: Filter{
+				{Table: "papers", Column: "published_year", Op: OpLt, Values: []Value{{Number: 2020, Kind: NumberValue}}},
+				{Table: "papers", Column: "published_year", Op: OpGt, Values: []Value{{Number: 2010, Kind: NumberValue}}},
+				{Table: "mentions", Column: "software_normalized", Op: OpEq, Values: []Value{{Text: "R", Kind: StringValue}}},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseFilter(tc.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"papers",
+		"papers.",
+		"papers published_year >= 2015",
+		"papers.2015 >= 2015",
+		"papers.published_year",
+		"papers.published_year >=",
+		"papers.published_year in 2015",
+		"papers.published_year in ()",
+		"papers.published_year in (2015 2016)",
+		"papers.published_year in (2015,",
+		"papers.published_year like 2015",
+		"papers.published_year >= year",
+		"papers.published_year >= 2015 papers.genre == true",
+		"papers.published_year >= 2015 &&",
+		"papers.published_year >= 1-2",
+	} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseFilter(s)
+			if !errors.Is(err, ErrFilter) {
+				t.Errorf("got error %v, want %v", err, ErrFilter)
+			}
+		})
+	}
+}
+
+func TestFilter_Tables(t *testing.T) {
+	filter, err := ParseFilter(`mentions.a == 1 && papers.b == 2 && mentions.c == 3 && mentions.a == 4`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"mentions", "papers"}, filter.Tables()); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"a", "c"}, filter.Predicate("mentions").Columns()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func testRecord(t *testing.T) arrow.Record {
+	t.Helper()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "year", Type: arrow.PrimitiveTypes.Uint16, Nullable: true},
+		{Name: "genre", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "open", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+
+	builder.Field(0).(*array.Uint16Builder).AppendValues([]uint16{2010, 2015, 0}, []bool{true, true, false})
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"article", "", "preprint"}, []bool{true, false, true})
+	builder.Field(2).(*array.BooleanBuilder).AppendValues([]bool{true, false, true}, nil)
+
+	record := builder.NewRecord()
+	t.Cleanup(record.Release)
+	return record
+}
+
+func TestPredicate_Bind(t *testing.T) {
+	tcs := []struct {
+		filter string
+		want   []bool
+	}{
+		{filter: "t.year >= 2015", want: []bool{false, true, false}},
+		{filter: "t.year < 2015", want: []bool{true, false, false}},
+		{filter: "t.year != 2015", want: []bool{true, false, false}},
+		{filter: `t.genre in ("article", "preprint")`, want: []bool{true, false, true}},
+		{filter: `t.genre > "b"`, want: []bool{false, false, true}},
+		{filter: "t.open == true", want: []bool{true, false, true}},
+		{filter: "t.open != true && t.year == 2015", want: []bool{false, true, false}},
+	}
+
+	record := testRecord(t)
+	for _, tc := range tcs {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := ParseFilter(tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			match, err := filter.Predicate("t").Bind(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]bool, record.NumRows())
+			for i := range got {
+				got[i] = match(i)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestPredicate_Bind_Errors(t *testing.T) {
+	record := testRecord(t)
+	for _, s := range []string{
+		"t.missing == 1",
+		`t.year == "2015"`,
+		"t.genre == 1",
+		"t.open < true",
+		"t.open == 1",
+	} {
+		t.Run(s, func(t *testing.T) {
+			filter, err := ParseFilter(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = filter.Predicate("t").Bind(record)
+			if !errors.Is(err, ErrFilter) {
+				t.Errorf("got error %v, want %v", err, ErrFilter)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package subsets
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"path/filepath"
+)
+
+// Purposes are the purpose assessments of the mentions of one source file
+// type. extract-columns writes them as rows of a purpose_assessments table, or
+// with --purpose-layout=wide as columns of the mentions table.
+type Purposes struct {
+	MentionsPath    string
+	AssessmentsPath string
+	// Wide is whether the assessments are columns of the mentions table.
+	Wide bool
+}
+
+// OpenPurposes finds the purpose assessments of the mentions of source in
+// inDir, telling the layouts apart by the columns of the mentions table rather
+// than by which files exist, so a purpose_assessments table left over from an
+// earlier extraction is never read in place of the mentions' own columns.
+func OpenPurposes(inDir, source string) (Purposes, error) {
+	p := Purposes{
+		MentionsPath:    filepath.Join(inDir, tables.MentionsName+"."+source+tables.ParquetExt),
+		AssessmentsPath: filepath.Join(inDir, tables.PurposeAssessmentsName+"."+source+tables.ParquetExt),
+	}
+
+	schema, err := readSchema(p.MentionsPath)
+	if err != nil {
+		return Purposes{}, err
+	}
+	p.Wide = schema.HasField(tables.PurposeScopeFieldName(tables.Purposes[0], tables.Scopes[0]))
+
+	return p, nil
+}
+
+func readSchema(inPath string) (*arrow.Schema, error) {
+	inFileReader, err := file.OpenParquetFile(inPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening parquet file %q: %w", ErrSubset, inPath, err)
+	}
+	defer func() {
+		_ = inFileReader.Close()
+	}()
+
+	inReader, err := pqarrow.NewFileReader(inFileReader, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return nil, fmt.Errorf("%w: creating pqarrow FileReader: %w", ErrSubset, err)
+	}
+
+	schema, err := inReader.Schema()
+	if err != nil {
+		return nil, fmt.Errorf("%w: getting schema of %q: %w", ErrSubset, inPath, err)
+	}
+	return schema, nil
+}
+
+// ScanScores calls fn with the certainty score of each purpose assessment,
+// stopping at the first error fn returns. Null scores are skipped.
+func (p Purposes) ScanScores(ctx context.Context, fn func(mentionId, scope, purpose string, score float64) error) error {
+	if p.Wide {
+		return p.scanWideScores(ctx, fn)
+	}
+
+	columns := []string{tables.SoftwareMentionIdFieldName, tables.ScopeFieldName, tables.PurposeFieldName, tables.CertaintyScoreFieldName}
+	return Scan(ctx, p.AssessmentsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		scopeColumn, err := Column[*array.Dictionary](record, tables.ScopeFieldName)
+		if err != nil {
+			return err
+		}
+		purposeColumn, err := Column[*array.Dictionary](record, tables.PurposeFieldName)
+		if err != nil {
+			return err
+		}
+		certaintyColumn, err := Column[*array.Float64](record, tables.CertaintyScoreFieldName)
+		if err != nil {
+			return err
+		}
+
+		for i, score := range certaintyColumn.Float64Values() {
+			if certaintyColumn.IsNull(i) {
+				continue
+			}
+			err = fn(mentionIdColumn.Value(i), scopeColumn.ValueStr(i), purposeColumn.ValueStr(i), score)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p Purposes) scanWideScores(ctx context.Context, fn func(mentionId, scope, purpose string, score float64) error) error {
+	columns := []string{tables.SoftwareMentionIdFieldName}
+	for _, purpose := range tables.Purposes {
+		for _, scope := range tables.Scopes {
+			columns = append(columns, tables.PurposeScopeFieldName(purpose, scope))
+		}
+	}
+
+	return Scan(ctx, p.MentionsPath, columns, func(record arrow.Record) error {
+		mentionIdColumn, err := Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+
+		for _, purpose := range tables.Purposes {
+			for _, scope := range tables.Scopes {
+				certaintyColumn, err := Column[*array.Float32](record, tables.PurposeScopeFieldName(purpose, scope))
+				if err != nil {
+					return err
+				}
+
+				for i, score := range certaintyColumn.Float32Values() {
+					if certaintyColumn.IsNull(i) {
+						continue
+					}
+					err = fn(mentionIdColumn.Value(i), scope, purpose, float64(score))
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// MatchingMentions returns the software_mention_id of the mentions with a
+// purpose assessment at scope of one of purposes with a certainty_score above
+// minCertainty, as PurposePredicate selects.
+func (p Purposes) MatchingMentions(ctx context.Context, scope string, purposes []string, minCertainty float64) (map[string]struct{}, error) {
+	predicate, err := PurposePredicate(scope, purposes, minCertainty)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Wide {
+		return MatchingMentions(ctx, p.AssessmentsPath, predicate)
+	}
+
+	columns := []string{tables.SoftwareMentionIdFieldName}
+	for _, purpose := range purposes {
+		columns = append(columns, tables.PurposeScopeFieldName(purpose, scope))
+	}
+
+	result := make(map[string]struct{})
+	err = Scan(ctx, p.MentionsPath, columns, func(record arrow.Record) error {
+		idColumn, err := Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+		if err != nil {
+			return err
+		}
+		certaintyColumns := make([]*array.Float32, len(purposes))
+		for i, purpose := range purposes {
+			certaintyColumns[i], err = Column[*array.Float32](record, tables.PurposeScopeFieldName(purpose, scope))
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := range idColumn.Len() {
+			for _, certaintyColumn := range certaintyColumns {
+				if !certaintyColumn.IsNull(i) && float64(certaintyColumn.Value(i)) > minCertainty {
+					result[idColumn.Value(i)] = struct{}{}
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,390 @@
+// Package subsets writes subsets of the rows of the Parquet tables, such as
+// the papers in a sample and their mentions.
+package subsets
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/compute"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/compress"
+	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"io"
+	"os"
+	"slices"
+	"sort"
+)
+
+const batchSize = 1 << 20
+
+// DefaultRowGroupSize is the default number of rows in each row group of
+// written subsets.
+const DefaultRowGroupSize = 1 << 17
+
+var ErrSubset = errors.New("writing subsets")
+
+// Selector chooses the rows of a record in each subset.
+type Selector interface {
+	// Select appends the indices of the rows of record in subset i to rows[i].
+	Select(record arrow.Record, rows [][]int64) error
+}
+
+// PaperSelector selects rows by their paper_id, with one set of paper ids per
+// subset.
+type PaperSelector []*Bitset
+
+func (s PaperSelector) Select(record arrow.Record, rows [][]int64) error {
+	idColumn, err := Column[*array.Uint32](record, tables.PaperIdFieldName)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range idColumn.Uint32Values() {
+		if idColumn.IsNull(i) {
+			continue
+		}
+
+		for j, subset := range s {
+			if subset.Contains(id) {
+				rows[j] = append(rows[j], int64(i))
+			}
+		}
+	}
+
+	return nil
+}
+
+// MentionSelector selects rows by their software_mention_id, with one set of
+// mention ids per subset.
+type MentionSelector []map[string]struct{}
+
+func (s MentionSelector) Select(record arrow.Record, rows [][]int64) error {
+	idColumn, err := Column[*array.String](record, tables.SoftwareMentionIdFieldName)
+	if err != nil {
+		return err
+	}
+
+	for i := range idColumn.Len() {
+		if idColumn.IsNull(i) {
+			continue
+		}
+
+		id := idColumn.Value(i)
+		for j, subset := range s {
+			if _, found := subset[id]; found {
+				rows[j] = append(rows[j], int64(i))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Column returns the column of record with the given name.
+func Column[T arrow.Array](record arrow.Record, name string) (T, error) {
+	var result T
+
+	indices := record.Schema().FieldIndices(name)
+	if len(indices) == 0 {
+		return result, fmt.Errorf("%w: missing column %q", ErrSubset, name)
+	}
+
+	result, ok := record.Column(indices[0]).(T)
+	if !ok {
+		return result, fmt.Errorf("%w: expected column %q to be of type %T, got %T", ErrSubset, name, result, record.Column(indices[0]))
+	}
+
+	return result, nil
+}
+
+// Scan calls fn with each batch of the named columns of the Parquet file at
+// inPath, or of all columns if columns is empty. Columns of the records are in
+// file order, so look them up with Column.
+func Scan(ctx context.Context, inPath string, columns []string, fn func(record arrow.Record) error) error {
+	inReader, columnIndices, err := newFileReader(inPath, columns)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = inReader.ParquetReader().Close()
+	}()
+
+	return scan(ctx, inPath, inReader, columnIndices, fn)
+}
+
+// newFileReader opens the Parquet file at inPath, returning the indices of the
+// named columns, or nil for all columns if columns is empty.
+func newFileReader(inPath string, columns []string) (*pqarrow.FileReader, []int, error) {
+	inFileReader, err := file.OpenParquetFile(inPath, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: opening parquet file %q: %w", ErrSubset, inPath, err)
+	}
+
+	inReader, err := pqarrow.NewFileReader(inFileReader,
+		pqarrow.ArrowReadProperties{Parallel: true, BatchSize: batchSize},
+		memory.NewGoAllocator(),
+	)
+	if err != nil {
+		_ = inFileReader.Close()
+		return nil, nil, fmt.Errorf("%w: creating pqarrow FileReader: %w", ErrSubset, err)
+	}
+
+	schema, err := inReader.Schema()
+	if err != nil {
+		_ = inFileReader.Close()
+		return nil, nil, fmt.Errorf("%w: getting schema: %w", ErrSubset, err)
+	}
+
+	var columnIndices []int
+	for _, name := range columns {
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			_ = inFileReader.Close()
+			return nil, nil, fmt.Errorf("%w: %q has no column %q", ErrSubset, inPath, name)
+		}
+		columnIndices = append(columnIndices, indices[0])
+	}
+	sort.Ints(columnIndices)
+	columnIndices = slices.Compact(columnIndices)
+
+	return inReader, columnIndices, nil
+}
+
+// scan calls fn with each batch of the given columns of inReader. pqarrow can't
+// read a dictionary column across row groups, whose dictionaries may differ, so
+// row groups are read one at a time.
+func scan(ctx context.Context, inPath string, inReader *pqarrow.FileReader, columnIndices []int, fn func(record arrow.Record) error) error {
+	for rowGroup := range inReader.ParquetReader().NumRowGroups() {
+		recordReader, err := inReader.GetRecordReader(ctx, columnIndices, []int{rowGroup})
+		if err != nil {
+			return fmt.Errorf("%w: getting record reader: %w", ErrSubset, err)
+		}
+
+		err = scanRecords(inPath, recordReader, fn)
+		recordReader.Release()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanRecords(inPath string, recordReader pqarrow.RecordReader, fn func(record arrow.Record) error) error {
+	var record arrow.Record
+	var err error
+	for record, err = recordReader.Read(); err == nil; record, err = recordReader.Read() {
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: reading records of %q: %w", ErrSubset, inPath, err)
+	}
+
+	return nil
+}
+
+// WriteOptions configures how subsets are written.
+type WriteOptions struct {
+	// Metadata, if not nil, is added to the key-value metadata of each subset.
+	Metadata []map[string]string
+	// RowGroupSize is the number of rows buffered before each row group is
+	// flushed. If zero, uses DefaultRowGroupSize.
+	RowGroupSize int64
+}
+
+// Write writes the rows of the Parquet file at inPath which selector chooses
+// for subset i to outPaths[i]. Rows are streamed, so at most one row group of
+// each subset is held in memory.
+func Write(ctx context.Context, inPath string, outPaths []string, selector Selector, opts WriteOptions) error {
+	allocator := memory.NewGoAllocator()
+
+	rowGroupSize := opts.RowGroupSize
+	if rowGroupSize == 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	inReader, _, err := newFileReader(inPath, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = inReader.ParquetReader().Close()
+	}()
+
+	schema, err := inReader.Schema()
+	if err != nil {
+		return fmt.Errorf("%w: getting schema: %w", ErrSubset, err)
+	}
+
+	writers := make([]*pqarrow.FileWriter, len(outPaths))
+	for i, outPath := range outPaths {
+		var md map[string]string
+		if opts.Metadata != nil {
+			md = opts.Metadata[i]
+		}
+
+		// Write the schema as read so that types only recoverable from the
+		// stored Arrow schema, such as dictionaries, survive.
+		writer, err := newWriter(schema, outPath, md, rowGroupSize)
+		if err != nil {
+			return err
+		}
+		writers[i] = writer
+
+		defer func() {
+			err := writer.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}()
+	}
+
+	rows := make([][]int64, len(outPaths))
+	return scan(ctx, inPath, inReader, nil, func(record arrow.Record) error {
+		for i := range rows {
+			rows[i] = rows[i][:0]
+		}
+		err := selector.Select(record, rows)
+		if err != nil {
+			return fmt.Errorf("%w: selecting rows of %q: %w", ErrSubset, inPath, err)
+		}
+
+		for i, writer := range writers {
+			if len(rows[i]) == 0 {
+				continue
+			}
+
+			err = writeRows(ctx, allocator, writer, record, rows[i])
+			if err != nil {
+				return fmt.Errorf("%w: writing %q: %w", ErrSubset, outPaths[i], err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// NewWriter creates a Parquet file at outPath for records of schema, with the
+// gzip compression and stored Arrow schema every table is written with. props
+// are applied after the defaults.
+func NewWriter(schema *arrow.Schema, outPath string, props ...parquet.WriterProperty) (*pqarrow.FileWriter, error) {
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return nil, err
+	}
+
+	props = append([]parquet.WriterProperty{
+		parquet.WithCompression(compress.Codecs.Gzip),
+		parquet.WithCompressionLevel(gzip.BestCompression),
+	}, props...)
+
+	// Don't close outFile; parquet handles closing it.
+	writer, err := pqarrow.NewFileWriter(
+		schema,
+		outFile,
+		parquet.NewWriterProperties(props...),
+		pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()),
+	)
+	if err != nil {
+		_ = outFile.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// WriteRecord writes the record built by recordBuilder to a new Parquet file at
+// outPath, as NewWriter does.
+func WriteRecord(outPath string, schema *arrow.Schema, recordBuilder *array.RecordBuilder) error {
+	writer, err := NewWriter(schema, outPath)
+	if err != nil {
+		return err
+	}
+
+	record := recordBuilder.NewRecord()
+	defer record.Release()
+
+	err = writer.Write(record)
+	return errors.Join(err, writer.Close())
+}
+
+func newWriter(schema *arrow.Schema, outPath string, metadata map[string]string, rowGroupSize int64) (*pqarrow.FileWriter, error) {
+	writer, err := NewWriter(schema, outPath, parquet.WithMaxRowGroupLength(rowGroupSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: creating writer for %q: %w", ErrSubset, outPath, err)
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		err = writer.AppendKeyValueMetadata(key, metadata[key])
+		if err != nil {
+			return nil, fmt.Errorf("%w: writing metadata to %q: %w", ErrSubset, outPath, err)
+		}
+	}
+
+	return writer, nil
+}
+
+// writeRows buffers the given rows of record in writer's current row group,
+// which writer flushes once it reaches the maximum row group length.
+func writeRows(ctx context.Context, allocator memory.Allocator, writer *pqarrow.FileWriter, record arrow.Record, rows []int64) error {
+	indicesBuilder := array.NewInt64Builder(allocator)
+	defer indicesBuilder.Release()
+	indicesBuilder.AppendValues(rows, nil)
+	indices := indicesBuilder.NewInt64Array()
+	defer indices.Release()
+
+	columns := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, column := range columns {
+			if column != nil {
+				column.Release()
+			}
+		}
+	}()
+
+	var err error
+	for i, column := range record.Columns() {
+		columns[i], err = takeColumn(ctx, column, indices)
+		if err != nil {
+			return fmt.Errorf("taking rows of column %q: %w", record.ColumnName(i), err)
+		}
+	}
+
+	taken := array.NewRecord(record.Schema(), columns, int64(len(rows)))
+	defer taken.Release()
+
+	return writer.WriteBuffered(taken)
+}
+
+// takeColumn selects the values of column at indices, preserving nulls.
+// The compute package has no kernel for dictionary arrays, so for those we
+// take the dictionary indices and reuse the dictionary.
+func takeColumn(ctx context.Context, column, indices arrow.Array) (arrow.Array, error) {
+	dictionary, ok := column.(*array.Dictionary)
+	if !ok {
+		return compute.TakeArray(ctx, column, indices)
+	}
+
+	takenIndices, err := compute.TakeArray(ctx, dictionary.Indices(), indices)
+	if err != nil {
+		return nil, err
+	}
+	defer takenIndices.Release()
+
+	return array.NewDictionaryArray(dictionary.DataType(), takenIndices, dictionary.Dictionary()), nil
+}
@@ -0,0 +1,238 @@
+package subsets
+
+import (
+	"context"
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/google/go-cmp/cmp"
+	"github.com/willbeason/software-mentions/pkg/tables"
+	"path/filepath"
+	"testing"
+)
+
+var softwareType = &arrow.DictionaryType{
+	IndexType: arrow.PrimitiveTypes.Uint8,
+	ValueType: arrow.BinaryTypes.String,
+}
+
+// stringValues returns the values of a string or dictionary of strings column,
+// with nulls as "<null>".
+func stringValues(column arrow.Array) []string {
+	result := make([]string, column.Len())
+	for i := range result {
+		if column.IsNull(i) {
+			result[i] = "<null>"
+		} else {
+			result[i] = column.ValueStr(i)
+		}
+	}
+	return result
+}
+
+func TestTakeColumn(t *testing.T) {
+	allocator := memory.NewGoAllocator()
+	ctx := context.Background()
+
+	dictionaryBuilder := array.NewDictionaryBuilder(allocator, softwareType).(*array.BinaryDictionaryBuilder)
+	defer dictionaryBuilder.Release()
+	for _, s := range []string{"R", "SPSS", "", "R"} {
+		if s == "" {
+			dictionaryBuilder.AppendNull()
+			continue
+		}
+		err := dictionaryBuilder.AppendString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dictionary := dictionaryBuilder.NewArray()
+	defer dictionary.Release()
+
+	int32Builder := array.NewInt32Builder(allocator)
+	defer int32Builder.Release()
+	int32Builder.AppendValues([]int32{1, 2, 0, 4}, []bool{true, true, false, true})
+	int32s := int32Builder.NewArray()
+	defer int32s.Release()
+
+	indicesBuilder := array.NewInt64Builder(allocator)
+	defer indicesBuilder.Release()
+	indicesBuilder.AppendValues([]int64{3, 2, 1, 2}, nil)
+	indices := indicesBuilder.NewArray()
+	defer indices.Release()
+
+	tcs := []struct {
+		name   string
+		column arrow.Array
+		want   []string
+	}{
+		{name: "dictionary", column: dictionary, want: []string{"R", "<null>", "SPSS", "<null>"}},
+		{name: "int32", column: int32s, want: []string{"4", "<null>", "2", "<null>"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := takeColumn(ctx, tc.column, indices)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer got.Release()
+
+			if !arrow.TypeEqual(tc.column.DataType(), got.DataType()) {
+				t.Errorf("got type %v, want %v", got.DataType(), tc.column.DataType())
+			}
+			if diff := cmp.Diff(tc.want, stringValues(got)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	// The dictionary is reused rather than copied.
+	got, err := takeColumn(ctx, dictionary, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Release()
+	if got.(*array.Dictionary).Dictionary().Data() != dictionary.(*array.Dictionary).Dictionary().Data() {
+		t.Error("taken dictionary is not the dictionary of the column")
+	}
+}
+
+// writeTestTable writes n rows with paper_id i and software "s<i%3>", or null
+// for every fourth row, to a new Parquet file, returning its path.
+func writeTestTable(t *testing.T, n int) string {
+	t.Helper()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: tables.PaperIdFieldName, Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "software", Type: softwareType, Nullable: true},
+	}, nil)
+
+	recordBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer recordBuilder.Release()
+	paperIdField := recordBuilder.Field(0).(*array.Uint32Builder)
+	softwareField := recordBuilder.Field(1).(*array.BinaryDictionaryBuilder)
+	for i := range n {
+		paperIdField.Append(uint32(i))
+		if i%4 == 3 {
+			softwareField.AppendNull()
+			continue
+		}
+		err := softwareField.AppendString([]string{"s0", "s1", "s2"}[i%3])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inPath := filepath.Join(t.TempDir(), "in"+tables.ParquetExt)
+	err := WriteRecord(inPath, schema, recordBuilder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return inPath
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	inPath := writeTestTable(t, 20)
+	// pqarrow reads dictionaries with int32 indices.
+	wantSchema, err := readSchema(inPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var even, small Bitset
+	for i := uint32(0); i < 20; i += 2 {
+		even.Add(i)
+	}
+	for i := uint32(0); i < 4; i++ {
+		small.Add(i)
+	}
+
+	outDir := t.TempDir()
+	outPaths := []string{
+		filepath.Join(outDir, "even"+tables.ParquetExt),
+		filepath.Join(outDir, "small"+tables.ParquetExt),
+	}
+	opts := WriteOptions{
+		Metadata:     []map[string]string{{"subset": "even"}, {"subset": "small"}},
+		RowGroupSize: 3,
+	}
+	err = Write(ctx, inPath, outPaths, PaperSelector{&even, &small}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		outPath       string
+		wantPaperIds  []string
+		wantSoftware  []string
+		wantRowGroups []int64
+		wantMetadata  string
+	}{
+		{
+			outPath:       outPaths[0],
+			wantPaperIds:  []string{"0", "2", "4", "6", "8", "10", "12", "14", "16", "18"},
+			wantSoftware:  []string{"s0", "s2", "s1", "s0", "s2", "s1", "s0", "s2", "s1", "s0"},
+			wantRowGroups: []int64{3, 3, 3, 1},
+			wantMetadata:  "even",
+		},
+		{
+			outPath:       outPaths[1],
+			wantPaperIds:  []string{"0", "1", "2", "3"},
+			wantSoftware:  []string{"s0", "s1", "s2", "<null>"},
+			wantRowGroups: []int64{3, 1},
+			wantMetadata:  "small",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.wantMetadata, func(t *testing.T) {
+			reader, err := file.OpenParquetFile(tc.outPath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = reader.Close()
+			}()
+
+			var rowGroups []int64
+			for i := range reader.NumRowGroups() {
+				rowGroups = append(rowGroups, reader.RowGroup(i).NumRows())
+			}
+			if diff := cmp.Diff(tc.wantRowGroups, rowGroups); diff != "" {
+				t.Errorf("row group sizes: %s", diff)
+			}
+
+			if got := reader.MetaData().KeyValueMetadata().FindValue("subset"); got == nil || *got != tc.wantMetadata {
+				t.Errorf("got subset metadata %v, want %q", got, tc.wantMetadata)
+			}
+
+			gotSchema, err := readSchema(tc.outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !gotSchema.Equal(wantSchema) {
+				t.Errorf("got schema %v, want %v", gotSchema, wantSchema)
+			}
+
+			var paperIds, software []string
+			err = Scan(context.Background(), tc.outPath, nil, func(record arrow.Record) error {
+				paperIds = append(paperIds, stringValues(record.Column(0))...)
+				software = append(software, stringValues(record.Column(1))...)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantPaperIds, paperIds); diff != "" {
+				t.Errorf("paper ids: %s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSoftware, software); diff != "" {
+				t.Errorf("software: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const SoftwareAliasesName = "software_aliases"
+
+var SoftwareAliases = arrow.NewSchema([]arrow.Field{
+	{Name: "alias",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "A software_normalized name of the software",
+		).Build()},
+	{Name: SoftwareCanonicalFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareCanonicalComment,
+		).Build()},
+	{Name: SoftwareIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareIdComment,
+		).Build()},
+	{Name: "confidence",
+		Type: arrow.PrimitiveTypes.Float64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The confidence, from 0.0 to 1.0, of the weakest rule joining the alias to the canonical name",
+		).Build()},
+	{Name: "rule",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment,
+			"The weakest rule joining the alias to the canonical name: canonical, override, case, wikidata, vendor, or edit_distance",
+		).Build()},
+	{Name: "mentions",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of mentions of the software by the alias",
+		).Build()},
+}, nil)
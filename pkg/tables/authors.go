@@ -0,0 +1,83 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const (
+	AuthorsName      = "authors"
+	PaperAuthorsName = "paper_authors"
+)
+
+const (
+	AuthorIdFieldName = "author_id"
+	authorIdComment   = "An id of the author, unique within this dataset. " +
+		"Authors with the same name and first affiliation after folding case and punctuation have the same id, " +
+		"so an author listed with different first affiliations has several ids."
+)
+
+var Authors = arrow.NewSchema([]arrow.Field{
+	{Name: AuthorIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, authorIdComment,
+		).Build()},
+	{Name: "given",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The given name of the author, as first seen",
+		).Build(),
+		Nullable: true},
+	{Name: "family",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The family name of the author, as first seen",
+		).Build(),
+		Nullable: true},
+	{Name: "papers",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of papers the author is an author of",
+		).Build()},
+}, nil)
+
+var PaperAuthors = arrow.NewSchema([]arrow.Field{
+	{Name: PaperIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, paperIdComment,
+		).Build()},
+	{Name: AuthorIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, authorIdComment,
+		).Build()},
+	{Name: "author_position",
+		Type: arrow.PrimitiveTypes.Uint16,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The position of the author in the paper's author list, starting at 0",
+		).Build()},
+	{Name: "sequence",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "Whether the author is the first author or an additional author",
+		).Build(),
+		Nullable: true},
+	{Name: "affiliations",
+		Type: arrow.ListOf(arrow.BinaryTypes.String),
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The affiliations of the author listed on the paper, as written",
+		).Build()},
+	{Name: "author_source",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The paper metadata the author is from: z_authors from Unpaywall, "+
+				"or glutton from Crossref if the paper has no z_authors",
+		).Build()},
+}, nil)
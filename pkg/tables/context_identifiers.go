@@ -0,0 +1,49 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const ContextIdentifiersName = "context_identifiers"
+
+var ContextIdentifiers = arrow.NewSchema([]arrow.Field{
+	{Name: SoftwareMentionIdFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareMentionIdComment,
+		).Build()},
+	{Name: PaperIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, paperIdComment,
+		).Build()},
+	{Name: "kind",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment,
+			"The type of identifier: rrid, doi, zenodo, swh, github, or version",
+		).Build()},
+	{Name: "value",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The normalized identifier, such as RRID:SCR_001905, a lower-case DOI, "+
+				"a GitHub repository URL, or a version like 2.3.1",
+		).Build()},
+	{Name: "raw",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The identifier as it appears in context_full_text",
+		).Build()},
+	{Name: "start",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The offset in characters of the first character of raw in context_full_text",
+		).Build()},
+	{Name: "end",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The offset in characters of the character after raw in context_full_text",
+		).Build()},
+}, nil)
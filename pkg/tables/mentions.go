@@ -5,20 +5,26 @@ import "github.com/apache/arrow/go/v18/arrow"
 const MentionsName = "mentions"
 
 const (
-	softwareMentionId = "software_mention_id"
-	sourceFileType    = "source_file_type"
-	mentionIndex      = "mention_index"
+	SoftwareMentionIdFieldName  = "software_mention_id"
+	SoftwareNormalizedFieldName = "software_normalized"
+	SoftwareWikidataFieldName   = "software_wikidata"
+	SoftwareCanonicalFieldName  = "software_canonical"
+	ContextFieldName            = "context_full_text"
+	sourceFileType              = "source_file_type"
+	MentionIndexFieldName       = "mention_index"
 )
 
 const (
 	softwareMentionIdComment = "A concatenation of paper_id, source_file_type, and mention_index"
 	sourceFileTypeComment    = "The extension of the source file parsed by SoftCite. " +
 		"There may be more than one source file per paper."
-	mentionIndexComment = "The index of the mention parsed from the source file"
+	mentionIndexComment      = "The index of the mention parsed from the source file"
+	softwareCanonicalComment = "The canonical name of the cluster of software_normalized names of the software, " +
+		"as listed in the software_aliases table"
 )
 
 var SoftwareMentions = arrow.NewSchema([]arrow.Field{
-	{Name: softwareMentionId,
+	{Name: SoftwareMentionIdFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, softwareMentionIdComment,
@@ -38,7 +44,7 @@ var SoftwareMentions = arrow.NewSchema([]arrow.Field{
 			comment,
 			sourceFileTypeComment,
 		).Build()},
-	{Name: mentionIndex,
+	{Name: MentionIndexFieldName,
 		Type: arrow.PrimitiveTypes.Uint16,
 		Metadata: NewMetadataBuilder().Add(
 			comment, mentionIndexComment,
@@ -48,7 +54,7 @@ var SoftwareMentions = arrow.NewSchema([]arrow.Field{
 		Metadata: NewMetadataBuilder().Add(
 			comment, "The raw string of the software mentioned",
 		).Build()},
-	{Name: "software_normalized",
+	{Name: SoftwareNormalizedFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, "A normalized string of the software mentioned",
@@ -101,10 +107,103 @@ var SoftwareMentions = arrow.NewSchema([]arrow.Field{
 			comment, "A normalized string of the a URL for the mentioned software",
 		).Build(),
 		Nullable: true},
-	{Name: "context_full_text",
+	{Name: ContextFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, "The software mention as it appears in the full text of the paper",
 		).Build(),
 		Nullable: true},
+	{Name: SoftwareWikidataFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The wikidata id SoftCite linked the mentioned software to",
+		).Build(),
+		Nullable: true},
+	{Name: SoftwareCanonicalFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareCanonicalComment,
+		).Build()},
+	{Name: SoftwareIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareIdComment,
+		).Build()},
+	{Name: "version_major",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The major version parsed from the mentioned software's version, or the year of a release like R2019b",
+		).Build(),
+		Nullable: true},
+	{Name: "version_minor",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The minor version parsed from the mentioned software's version, or 1 or 2 for the a or b of a release like R2019b",
+		).Build(),
+		Nullable: true},
+	{Name: "version_patch",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The patch version parsed from the mentioned software's version",
+		).Build(),
+		Nullable: true},
+	{Name: "version_prerelease",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The prerelease tag parsed from the mentioned software's version, such as beta2 or rc1",
+		).Build(),
+		Nullable: true},
+	{Name: "version_release",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The vendor-style release name parsed from the mentioned software's version, such as R2019b",
+		).Build(),
+		Nullable: true},
+	{Name: "version_sort_key",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "A string which sorts and compares like the parsed version, with prereleases before their release",
+		).Build(),
+		Nullable: true},
 }, NewMetadataBuilder().BuildReference())
+
+// PurposeScopeFieldName is the name of the SoftwareMentionsWide column with
+// the certainty score of a purpose and scope.
+func PurposeScopeFieldName(purpose, scope string) string {
+	return purpose + "_" + scope
+}
+
+// PurposeScopeDecisionFieldName is the name of the SoftwareMentionsWide column
+// with the decision of a purpose and scope.
+func PurposeScopeDecisionFieldName(purpose, scope string) string {
+	return PurposeScopeFieldName(purpose, scope) + "_" + DecisionFieldName
+}
+
+// SoftwareMentionsWide is SoftwareMentions with the purpose assessments of each
+// mention as columns, rather than as rows of a PurposeAssessment table. For
+// each purpose and scope there is a certainty score column followed by a
+// decision column, ordered by purpose, then scope, as in Purposes and Scopes.
+var SoftwareMentionsWide = arrow.NewSchema(append(SoftwareMentions.Fields(), purposeScopeFields()...), nil)
+
+func purposeScopeFields() []arrow.Field {
+	var fields []arrow.Field
+	for _, purpose := range Purposes {
+		for _, scope := range Scopes {
+			fields = append(fields,
+				arrow.Field{Name: PurposeScopeFieldName(purpose, scope),
+					Type: arrow.PrimitiveTypes.Float32,
+					Metadata: NewMetadataBuilder().Add(
+						comment, "The confidence SoftCite model has that the software is "+purpose+
+							" in the "+scope+" scope of this mention, from 0.0 to 1.0",
+					).Build()},
+				arrow.Field{Name: PurposeScopeDecisionFieldName(purpose, scope),
+					Type: arrow.FixedWidthTypes.Boolean,
+					Metadata: NewMetadataBuilder().Add(
+						comment, "Whether the SoftCite model decided that the software is "+purpose+
+							" in the "+scope+" scope of this mention",
+					).Build()},
+			)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,106 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const OaLocationsName = "oa_locations"
+
+const IsBestFieldName = "is_best"
+
+var OaLocations = arrow.NewSchema([]arrow.Field{
+	{Name: PaperIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, paperIdComment,
+		).Build()},
+	{Name: "location_index",
+		Type: arrow.PrimitiveTypes.Uint16,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The position of the location in the paper's Unpaywall oa_locations, starting at 0",
+		).Build()},
+	{Name: IsBestFieldName,
+		Type: arrow.FixedWidthTypes.Boolean,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "Whether Unpaywall chose this location as the best open access location of the paper",
+		).Build()},
+	{Name: "url",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The URL of the open access copy, the PDF if there is one and otherwise the landing page",
+		).Build()},
+	{Name: "url_for_pdf",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The URL of the PDF of the open access copy",
+		).Build(),
+		Nullable: true},
+	{Name: "url_for_landing_page",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The URL of the landing page of the open access copy",
+		).Build(),
+		Nullable: true},
+	{Name: "host_type",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "Whether the copy is hosted by the publisher or a repository",
+		).Build(),
+		Nullable: true},
+	{Name: "version",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The version of the paper: submittedVersion, acceptedVersion, or publishedVersion",
+		).Build(),
+		Nullable: true},
+	{Name: "license",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The license of the copy according to Unpaywall, such as cc-by",
+		).Build(),
+		Nullable: true},
+	{Name: "evidence",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "How Unpaywall found the copy, such as oa journal (via doaj)",
+		).Build(),
+		Nullable: true},
+	{Name: "repository_institution",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The institution hosting the repository of the copy",
+		).Build(),
+		Nullable: true},
+	{Name: "pmh_id",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The OAI-PMH id of the copy in its repository",
+		).Build(),
+		Nullable: true},
+	{Name: "endpoint_id",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The Unpaywall id of the repository endpoint the copy was harvested from",
+		).Build(),
+		Nullable: true},
+	{Name: "oa_date",
+		Type: arrow.FixedWidthTypes.Date32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The date the copy first became open access",
+		).Build(),
+		Nullable: true},
+}, nil)
@@ -0,0 +1,94 @@
+package tables
+
+import (
+	"fmt"
+	"github.com/apache/arrow/go/v18/arrow"
+)
+
+const PaperSoftwareName = "paper_software"
+
+const (
+	MentionsFieldName          = "mentions"
+	FirstMentionIndexFieldName = "first_mention_index"
+)
+
+// Scopes and Purposes are the scopes and purposes of purpose assessments, in
+// the order of the columns of PaperSoftware.
+var (
+	Scopes   = []string{"document", "local"}
+	Purposes = []string{"used", "created", "shared"}
+)
+
+// aggregationComments describe the aggregations of certainty scores in
+// PaperSoftware.
+var aggregationComments = map[string]string{
+	"max":   "The largest certainty_score",
+	"mean":  "The mean certainty_score",
+	"min":   "The smallest certainty_score",
+	"count": "The number of mentions with a certainty_score above the rollup threshold",
+}
+
+// Aggregations are the aggregations of certainty scores PaperSoftware may have
+// columns for.
+var Aggregations = []string{"max", "mean", "min", "count"}
+
+// CertaintyFieldName is the name of the PaperSoftware column aggregating the
+// certainty scores of a purpose and scope.
+func CertaintyFieldName(purpose, scope, aggregation string) string {
+	return PurposeScopeFieldName(purpose, scope) + "_" + aggregation
+}
+
+// PaperSoftware returns the schema of the paper_software table with a column
+// for each aggregation of the certainty scores of each purpose and scope.
+// Aggregations must be in Aggregations.
+func PaperSoftware(aggregations []string) *arrow.Schema {
+	fields := []arrow.Field{
+		{Name: PaperIdFieldName,
+			Type: arrow.PrimitiveTypes.Uint32,
+			Metadata: NewMetadataBuilder().Add(
+				comment, paperIdComment,
+			).Build()},
+		{Name: "software",
+			Type: arrow.BinaryTypes.String,
+			Metadata: NewMetadataBuilder().Add(
+				comment, "The software mentioned in the paper",
+			).Build()},
+		{Name: MentionsFieldName,
+			Type: arrow.PrimitiveTypes.Uint32,
+			Metadata: NewMetadataBuilder().Add(
+				comment, "The number of mentions of the software in the paper",
+			).Build()},
+		{Name: FirstMentionIndexFieldName,
+			Type: arrow.PrimitiveTypes.Uint16,
+			Metadata: NewMetadataBuilder().Add(
+				comment, "The smallest mention_index of the mentions of the software in the paper",
+			).Build()},
+	}
+
+	for _, purpose := range Purposes {
+		for _, scope := range Scopes {
+			for _, aggregation := range aggregations {
+				field := arrow.Field{
+					Name: CertaintyFieldName(purpose, scope, aggregation),
+					Type: arrow.PrimitiveTypes.Float64,
+					Metadata: NewMetadataBuilder().Add(
+						comment, fmt.Sprintf("%s of the %s %s purpose assessments of the mentions. "+
+							"Null if the mentions have none.", aggregationComments[aggregation], scope, purpose),
+					).Build(),
+					Nullable: true,
+				}
+				if aggregation == "count" {
+					field.Type = arrow.PrimitiveTypes.Uint32
+					field.Metadata = NewMetadataBuilder().Add(
+						comment, fmt.Sprintf("%s of the %s %s purpose assessments of the mentions",
+							aggregationComments[aggregation], scope, purpose),
+					).Build()
+					field.Nullable = false
+				}
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return arrow.NewSchema(fields, nil)
+}
@@ -3,10 +3,14 @@ package tables
 import "github.com/apache/arrow/go/v18/arrow"
 
 const (
-	PapersName           = "papers"
-	PaperIdFieldName     = "paper_id"
-	HasMentionsFieldName = "has_mentions"
-	paperIdComment       = "A unique identifier for the paper in this dataset"
+	PapersName                = "papers"
+	PaperIdFieldName          = "paper_id"
+	SoftciteIdFieldName       = "softcite_id"
+	HasMentionsFieldName      = "has_mentions"
+	PublishedYearFieldName    = "published_year"
+	PublicationVenueFieldName = "publication_venue"
+	OaStatusFieldName         = "oa_status"
+	paperIdComment            = "A unique identifier for the paper in this dataset"
 )
 
 var Papers = arrow.NewSchema([]arrow.Field{
@@ -16,7 +20,7 @@ var Papers = arrow.NewSchema([]arrow.Field{
 			comment, paperIdComment,
 		).Build(),
 	},
-	{Name: "softcite_id",
+	{Name: SoftciteIdFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, "The UUID of the paper in SoftCite",
@@ -29,7 +33,7 @@ var Papers = arrow.NewSchema([]arrow.Field{
 		).Build(),
 		Nullable: true,
 	},
-	{Name: "published_year",
+	{Name: PublishedYearFieldName,
 		Type: arrow.PrimitiveTypes.Uint16,
 		Metadata: NewMetadataBuilder().Add(
 			comment, "The parsed publication year of the paper",
@@ -43,7 +47,7 @@ var Papers = arrow.NewSchema([]arrow.Field{
 		).Build(),
 		Nullable: true,
 	},
-	{Name: "publication_venue",
+	{Name: PublicationVenueFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, "The parsed venue the paper was published in",
@@ -59,7 +63,7 @@ var Papers = arrow.NewSchema([]arrow.Field{
 	{Name: "doi",
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
-			comment, "The doi of the paper",
+			comment, "The doi of the paper, lower-cased and without a resolver prefix",
 		).Build(),
 	},
 	{Name: "pmcid",
@@ -94,7 +98,18 @@ var Papers = arrow.NewSchema([]arrow.Field{
 			Ordered:   false,
 		},
 		Metadata: NewMetadataBuilder().Add(
-			comment, "A normalized string of the license under which the paper was published",
+			comment, "The license under which the paper was published, as written in the paper metadata",
+		).Build(),
+		Nullable: true,
+	},
+	{Name: "license_spdx",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The SPDX identifier of license_type, or a LicenseRef- identifier for licenses without one, such as unversioned Creative Commons licenses",
 		).Build(),
 		Nullable: true,
 	},
@@ -104,6 +119,31 @@ var Papers = arrow.NewSchema([]arrow.Field{
 			comment, "Whether any mentions exist for this paper",
 		).Build(),
 	},
+	{Name: OaStatusFieldName,
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The open access route of the paper according to Unpaywall: gold, green, hybrid, bronze, or closed",
+		).Build(),
+		Nullable: true,
+	},
+	{Name: "journal_is_oa",
+		Type: arrow.FixedWidthTypes.Boolean,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "Whether the journal the paper was published in is fully open access, according to Unpaywall",
+		).Build(),
+		Nullable: true,
+	},
+	{Name: "has_repository_copy",
+		Type: arrow.FixedWidthTypes.Boolean,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "Whether an open access copy of the paper is in a repository, according to Unpaywall",
+		).Build(),
+		Nullable: true,
+	},
 }, NewMetadataBuilder().Add(
 	comment, "Papers from the SoftCite dataset",
 ).BuildReference())
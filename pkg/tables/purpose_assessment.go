@@ -4,8 +4,15 @@ import "github.com/apache/arrow/go/v18/arrow"
 
 var PurposeAssessmentsName = "purpose_assessments"
 
+const (
+	ScopeFieldName          = "scope"
+	PurposeFieldName        = "purpose"
+	CertaintyScoreFieldName = "certainty_score"
+	DecisionFieldName       = "decision"
+)
+
 var PurposeAssessment = arrow.NewSchema([]arrow.Field{
-	{Name: softwareMentionId,
+	{Name: SoftwareMentionIdFieldName,
 		Type: arrow.BinaryTypes.String,
 		Metadata: NewMetadataBuilder().Add(
 			comment, softwareMentionIdComment,
@@ -25,12 +32,12 @@ var PurposeAssessment = arrow.NewSchema([]arrow.Field{
 			comment,
 			sourceFileTypeComment,
 		).Build()},
-	{Name: mentionIndex,
+	{Name: MentionIndexFieldName,
 		Type: arrow.PrimitiveTypes.Uint16,
 		Metadata: NewMetadataBuilder().Add(
 			comment, mentionIndexComment,
 		).Build()},
-	{Name: "scope",
+	{Name: ScopeFieldName,
 		Type: &arrow.DictionaryType{
 			IndexType: arrow.PrimitiveTypes.Uint8,
 			ValueType: arrow.BinaryTypes.String,
@@ -40,7 +47,7 @@ var PurposeAssessment = arrow.NewSchema([]arrow.Field{
 			comment,
 			"Whether the assessment is about the local or document-level scope",
 		).Build()},
-	{Name: "purpose",
+	{Name: PurposeFieldName,
 		Type: &arrow.DictionaryType{
 			IndexType: arrow.PrimitiveTypes.Uint8,
 			ValueType: arrow.BinaryTypes.String,
@@ -50,10 +57,16 @@ var PurposeAssessment = arrow.NewSchema([]arrow.Field{
 			comment,
 			"Whether the assessment is about the software being used, created, or shared in this paper",
 		).Build()},
-	{Name: "certainty_score",
+	{Name: CertaintyScoreFieldName,
 		Type: arrow.PrimitiveTypes.Float64,
 		Metadata: NewMetadataBuilder().Add(
 			comment,
 			"The confidence SoftCite model has that this is the purpose of this mention, from 0.0 to 1.0",
 		).Build()},
+	{Name: DecisionFieldName,
+		Type: arrow.FixedWidthTypes.Boolean,
+		Metadata: NewMetadataBuilder().Add(
+			comment,
+			"Whether the SoftCite model decided that this is the purpose of this mention, by its own threshold on certainty_score",
+		).Build()},
 }, nil)
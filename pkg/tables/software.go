@@ -0,0 +1,68 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const SoftwareName = "software"
+
+const SoftwareIdFieldName = "software_id"
+
+const softwareIdComment = "A stable identifier of the software, the 64-bit FNV-1a hash of its canonical name"
+
+var Software = arrow.NewSchema([]arrow.Field{
+	{Name: SoftwareIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareIdComment,
+		).Build()},
+	{Name: SoftwareCanonicalFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareCanonicalComment,
+		).Build()},
+	{Name: SoftwareWikidataFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The wikidata id the software is most often linked to",
+		).Build(),
+		Nullable: true},
+	{Name: "publisher",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The publisher most often mentioned with the software",
+		).Build(),
+		Nullable: true},
+	{Name: "language",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The programming language most often mentioned with the software",
+		).Build(),
+		Nullable: true},
+	{Name: "url",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The URL most often mentioned with the software",
+		).Build(),
+		Nullable: true},
+	{Name: "first_year",
+		Type: arrow.PrimitiveTypes.Uint16,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The earliest published year of a paper mentioning the software",
+		).Build(),
+		Nullable: true},
+	{Name: "last_year",
+		Type: arrow.PrimitiveTypes.Uint16,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The latest published year of a paper mentioning the software",
+		).Build(),
+		Nullable: true},
+	{Name: "mentions",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of mentions of the software",
+		).Build()},
+	{Name: "papers",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of papers mentioning the software",
+		).Build()},
+}, nil)
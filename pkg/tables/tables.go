@@ -1,6 +1,5 @@
 package tables
 
 const (
-	Software   = "software"
 	ParquetExt = ".parquet"
 )
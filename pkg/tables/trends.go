@@ -0,0 +1,48 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const SoftwareTrendsName = "software_trends"
+
+var SoftwareTrends = arrow.NewSchema([]arrow.Field{
+	{Name: "software",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The software mentioned",
+		).Build()},
+	{Name: "year",
+		Type: arrow.PrimitiveTypes.Uint16,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The published year of the papers",
+		).Build()},
+	{Name: "papers",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of papers published in the year which mention the software",
+		).Build()},
+	{Name: "mentions",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of mentions of the software in papers published in the year",
+		).Build()},
+	{Name: "used_papers",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of papers published in the year with a mention of the software assessed as used",
+		).Build()},
+	{Name: "total_papers",
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The number of papers published in the year",
+		).Build()},
+	{Name: "papers_share",
+		Type: arrow.PrimitiveTypes.Float64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "papers divided by total_papers",
+		).Build()},
+	{Name: "used_papers_share",
+		Type: arrow.PrimitiveTypes.Float64,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "used_papers divided by total_papers",
+		).Build()},
+}, nil)
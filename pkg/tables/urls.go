@@ -0,0 +1,73 @@
+package tables
+
+import "github.com/apache/arrow/go/v18/arrow"
+
+const MentionUrlsName = "mention_urls"
+
+var MentionUrls = arrow.NewSchema([]arrow.Field{
+	{Name: SoftwareMentionIdFieldName,
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, softwareMentionIdComment,
+		).Build()},
+	{Name: PaperIdFieldName,
+		Type: arrow.PrimitiveTypes.Uint32,
+		Metadata: NewMetadataBuilder().Add(
+			comment, paperIdComment,
+		).Build()},
+	{Name: "url",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The url_normalized of the mention, or its url_raw if it has none",
+		).Build()},
+	{Name: "url_canonical",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The URL with a lower-case scheme and host, and without www., fragments, tracking parameters, "+
+				"or trailing slashes. Null if the URL could not be parsed.",
+		).Build(),
+		Nullable: true},
+	{Name: "host",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The lower-case host of the URL, without www. or a port",
+		).Build(),
+		Nullable: true},
+	{Name: "kind",
+		Type: &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Uint8,
+			ValueType: arrow.BinaryTypes.String,
+			Ordered:   false,
+		},
+		Metadata: NewMetadataBuilder().Add(
+			comment,
+			"The type of site the URL points to: github, gitlab, bitbucket, cran, pypi, bioconductor, zenodo, "+
+				"sourceforge, personal, or other",
+		).Build(),
+		Nullable: true},
+	{Name: "owner",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The lower-case user or organization owning the repository, or the user of a personal site",
+		).Build(),
+		Nullable: true},
+	{Name: "name",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The repository, package, project, or Zenodo record the URL points to",
+		).Build(),
+		Nullable: true},
+	{Name: "repository_url",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The canonical URL of the repository or package page, shared by every URL of the same "+
+				"repository or package",
+		).Build(),
+		Nullable: true},
+	{Name: "doi",
+		Type: arrow.BinaryTypes.String,
+		Metadata: NewMetadataBuilder().Add(
+			comment, "The DOI of the Zenodo record the URL points to",
+		).Build(),
+		Nullable: true},
+}, nil)
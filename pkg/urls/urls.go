@@ -0,0 +1,275 @@
+// Package urls canonicalizes the URLs software is mentioned with and
+// classifies them by the code host, package registry, or archive they point
+// to, such as GitHub, CRAN, or Zenodo.
+package urls
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalid = errors.New("invalid URL")
+
+// Kind is the type of site a URL points to.
+type Kind string
+
+const (
+	KindGithub       Kind = "github"
+	KindGitlab       Kind = "gitlab"
+	KindBitbucket    Kind = "bitbucket"
+	KindCran         Kind = "cran"
+	KindPypi         Kind = "pypi"
+	KindBioconductor Kind = "bioconductor"
+	KindZenodo       Kind = "zenodo"
+	KindSourceforge  Kind = "sourceforge"
+	// KindPersonal URLs are personal or project homepages, such as GitHub
+	// Pages sites and "~user" directories of university sites.
+	KindPersonal Kind = "personal"
+	// KindOther URLs are on any other site.
+	KindOther Kind = "other"
+)
+
+// URL is a canonicalized and classified URL.
+type URL struct {
+	// Canonical is the URL with an explicit lower-case scheme and host, without
+	// "www.", fragments, tracking parameters, or trailing slashes.
+	Canonical string
+	// Host is the lower-case host of the URL, without "www." or a port.
+	Host string
+	Kind Kind
+
+	// Owner is the user or organization owning a repository, if Kind is a code
+	// host, or the user of a personal site.
+	Owner string
+	// Name is the repository, package, project, or record the URL points to.
+	Name string
+	// Repository is the canonical URL of the repository or package page the URL
+	// is in, shared by every URL of the same repository or package.
+	Repository string
+	// Doi is the DOI of Zenodo records.
+	Doi string
+}
+
+const zenodoDoiPrefix = "10.5281/zenodo."
+
+var (
+	// schemePattern matches URLs which begin with a scheme.
+	schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	// zenodoRecordPattern matches the path of Zenodo records.
+	zenodoRecordPattern = regexp.MustCompile(`^/records?/(\d+)`)
+	// zenodoDoiPattern matches the path of Zenodo DOIs on doi.org.
+	zenodoDoiPattern = regexp.MustCompile(`^/10\.5281/zenodo\.(\d+)`)
+	// personalPathPattern matches the paths of "~user" directories.
+	personalPathPattern = regexp.MustCompile(`^/~([^/]+)`)
+	// pypiSeparatorPattern matches runs of the characters PyPI treats alike.
+	pypiSeparatorPattern = regexp.MustCompile(`[-_.]+`)
+)
+
+// personalHostPrefixes are subdomains universities host personal pages on.
+var personalHostPrefixes = []string{"people.", "homepages.", "users.", "staff.", "faculty."}
+
+// personalHostSuffixes are sites which host personal pages on subdomains.
+var personalHostSuffixes = []string{".github.io", ".gitlab.io", ".wordpress.com", ".netlify.app"}
+
+// Parse canonicalizes and classifies raw. Whitespace within raw, which PDF
+// text extraction often inserts into long URLs, is removed.
+func Parse(raw string) (URL, error) {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, raw)
+	s = strings.TrimLeft(s, "<([{\"'")
+	s = strings.TrimRight(s, ".,;:>)]}\"'")
+	if s == "" {
+		return URL{}, fmt.Errorf("%w: %q is empty", ErrInvalid, raw)
+	}
+	if !schemePattern.MatchString(s) {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return URL{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "http", "https", "ftp":
+	default:
+		return URL{}, fmt.Errorf("%w: %q has unsupported scheme %q", ErrInvalid, raw, u.Scheme)
+	}
+
+	if u.User != nil {
+		// Most often an email address, like "mailto:name@example.com".
+		return URL{}, fmt.Errorf("%w: %q has user information", ErrInvalid, raw)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if !strings.Contains(host, ".") {
+		return URL{}, fmt.Errorf("%w: %q has no domain", ErrInvalid, raw)
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+
+	result := URL{Host: host, Kind: KindOther}
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	classify(&result, segments, path, query)
+
+	if result.Kind != KindOther && result.Kind != KindPersonal {
+		// Code hosts and registries all serve https.
+		scheme = "https"
+	}
+	canonical := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		RawQuery: query.Encode(),
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		canonical.Host += ":" + port
+	}
+	canonical.RawPath = path
+	canonical.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return URL{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	result.Canonical = canonical.String()
+
+	return result, nil
+}
+
+// classify sets the Kind, Owner, Name, Repository, and Doi of result from its
+// Host and the path of the URL.
+func classify(result *URL, segments []string, path string, query url.Values) {
+	segment := func(i int) string {
+		if i < len(segments) {
+			return segments[i]
+		}
+		return ""
+	}
+
+	switch host := result.Host; {
+	case host == "github.com", host == "gitlab.com", host == "bitbucket.org":
+		result.Kind = map[string]Kind{
+			"github.com":    KindGithub,
+			"gitlab.com":    KindGitlab,
+			"bitbucket.org": KindBitbucket,
+		}[host]
+		// Owners and repositories are case-insensitive on all three hosts.
+		result.Owner = strings.ToLower(segment(0))
+		result.Name = strings.ToLower(strings.TrimSuffix(segment(1), ".git"))
+		if result.Owner != "" && result.Name != "" && result.Name != "-" {
+			result.Repository = "https://" + host + "/" + result.Owner + "/" + result.Name
+		} else {
+			result.Name = ""
+		}
+
+	case host == "cran.r-project.org", host == "cran.rstudio.com":
+		result.Kind = KindCran
+		switch {
+		case query.Get("package") != "":
+			result.Name = query.Get("package")
+		case strings.HasPrefix(segment(0), "package="):
+			result.Name = strings.TrimPrefix(segment(0), "package=")
+		case segment(0) == "web" && segment(1) == "packages":
+			result.Name = segment(2)
+		case segment(0) == "package":
+			result.Name = segment(1)
+		}
+		if result.Name != "" {
+			result.Repository = "https://cran.r-project.org/package=" + result.Name
+		}
+
+	case host == "pypi.org", host == "pypi.python.org":
+		result.Kind = KindPypi
+		if segment(0) == "project" || segment(0) == "pypi" {
+			// PyPI names are case-insensitive and treat runs of "-", "_", and
+			// "." alike.
+			result.Name = strings.ToLower(pypiSeparatorPattern.ReplaceAllString(segment(1), "-"))
+		}
+		if result.Name != "" {
+			result.Repository = "https://pypi.org/project/" + result.Name
+		}
+
+	case host == "bioconductor.org":
+		result.Kind = KindBioconductor
+		if segment(0) == "packages" && len(segments) > 1 {
+			// Package pages are /packages/NAME or
+			// /packages/RELEASE/bioc/html/NAME.html.
+			result.Name = strings.TrimSuffix(segments[len(segments)-1], ".html")
+		}
+		if result.Name != "" {
+			result.Repository = "https://bioconductor.org/packages/" + result.Name
+		}
+
+	case host == "zenodo.org":
+		result.Kind = KindZenodo
+		if match := zenodoRecordPattern.FindStringSubmatch(path); match != nil {
+			result.Name = match[1]
+		}
+
+	case host == "doi.org", host == "dx.doi.org":
+		if match := zenodoDoiPattern.FindStringSubmatch(strings.ToLower(path)); match != nil {
+			result.Kind = KindZenodo
+			result.Name = match[1]
+		}
+
+	case host == "sourceforge.net":
+		result.Kind = KindSourceforge
+		if segment(0) == "projects" || segment(0) == "p" {
+			result.Name = strings.ToLower(segment(1))
+		}
+
+	case strings.HasSuffix(host, ".sourceforge.net"), strings.HasSuffix(host, ".sourceforge.io"):
+		result.Kind = KindSourceforge
+		result.Name = host[:strings.Index(host, ".")]
+
+	default:
+		for _, suffix := range personalHostSuffixes {
+			if owner, found := strings.CutSuffix(host, suffix); found && !strings.Contains(owner, ".") {
+				result.Kind = KindPersonal
+				result.Owner = owner
+				return
+			}
+		}
+		if match := personalPathPattern.FindStringSubmatch(path); match != nil {
+			result.Kind = KindPersonal
+			result.Owner = match[1]
+			return
+		}
+		for _, prefix := range personalHostPrefixes {
+			if strings.HasPrefix(host, prefix) {
+				result.Kind = KindPersonal
+				result.Owner = segment(0)
+				return
+			}
+		}
+	}
+
+	switch result.Kind {
+	case KindZenodo:
+		if result.Name != "" {
+			result.Doi = zenodoDoiPrefix + result.Name
+			result.Repository = "https://doi.org/" + result.Doi
+		}
+	case KindSourceforge:
+		if result.Name != "" {
+			result.Repository = "https://sourceforge.net/projects/" + result.Name
+		}
+	}
+}
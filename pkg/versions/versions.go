@@ -0,0 +1,173 @@
+// Package versions parses the version strings software is mentioned with, such
+// as "v2.3.1", "version 9.4 (SAS Institute)", and "R2019b", into comparable
+// components.
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed version string.
+type Version struct {
+	// Components is the number of numeric components parsed, from 1 to 3.
+	// Components past it are zero.
+	Components int
+	Major      int
+	Minor      int
+	Patch      int
+
+	// Prerelease is a normalized prerelease tag, such as "beta2" or "rc1".
+	Prerelease string
+
+	// Release is a vendor-style release name, such as MATLAB's "R2019b", which
+	// is also parsed into Major and Minor.
+	Release string
+}
+
+var (
+	// releasePattern matches MATLAB-style releases, numbered by year and half.
+	releasePattern = regexp.MustCompile(`(?i)\bR(\d{4})([ab])\b`)
+	numberPattern  = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+	// markerPattern matches a word introducing a version at the end of the
+	// text before a number, as in "v2.3" or "version 9.4".
+	markerPattern = regexp.MustCompile(`(?i)\b(?:version|ver|v)\.?\s*$`)
+	// bitsPattern matches an architecture at the start of the text after a
+	// number, as in "64-bit", which is never the version.
+	bitsPattern = regexp.MustCompile(`(?i)^[- ]?bits?\b`)
+	// prereleasePattern matches a prerelease tag directly after the numbers.
+	prereleasePattern = regexp.MustCompile(`(?i)^[-._ ]?(dev|alpha|beta|rc|pre|a|b)[-._]?(\d{0,4})\b`)
+)
+
+// prereleaseRanks order prerelease tags before the releases they precede.
+var prereleaseRanks = map[string]int{
+	"dev":   0,
+	"pre":   1,
+	"alpha": 2,
+	"beta":  3,
+	"rc":    4,
+}
+
+const releaseRank = 9
+
+// maxComponent is the largest component which fits in a SortKey.
+const maxComponent = 99999999
+
+// Parse returns the version in s, or false if s has none.
+//
+// If s has several numbers, the version is the first number after a word like
+// "v" or "version", then the first number which is not an architecture like
+// "64-bit". So "Windows 10 version 2.3" is 2.3 and
+// "SPSS 64-bit 22" is 22.
+func Parse(s string) (Version, bool) {
+	if match := releasePattern.FindStringSubmatch(s); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		half := 1
+		if strings.EqualFold(match[2], "b") {
+			half = 2
+		}
+		return Version{
+			Components: 2,
+			Major:      year,
+			Minor:      half,
+			Release:    "R" + match[1] + strings.ToLower(match[2]),
+		}, true
+	}
+
+	indices := versionNumber(s)
+	if indices == nil {
+		return Version{}, false
+	}
+
+	var result Version
+	for i, component := range []*int{&result.Major, &result.Minor, &result.Patch} {
+		start, end := indices[2+2*i], indices[3+2*i]
+		if start < 0 {
+			break
+		}
+		value, err := strconv.Atoi(s[start:end])
+		if err != nil || value > maxComponent {
+			// Too many digits to be a version component.
+			return Version{}, false
+		}
+		*component = value
+		result.Components++
+	}
+
+	if match := prereleasePattern.FindStringSubmatch(s[indices[1]:]); match != nil {
+		tag := strings.ToLower(match[1])
+		switch tag {
+		case "a":
+			tag = "alpha"
+		case "b":
+			tag = "beta"
+		}
+		result.Prerelease = tag + match[2]
+	}
+
+	return result, true
+}
+
+// versionNumber returns the submatch indices of the number in s which is most
+// likely its version, or nil if s has no numbers.
+func versionNumber(s string) []int {
+	matches := numberPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	for _, match := range matches {
+		if markerPattern.MatchString(s[:match[0]]) {
+			return match
+		}
+	}
+	// Architectures are skipped, even starting s.
+	var numbers [][]int
+	for _, match := range matches {
+		if !bitsPattern.MatchString(s[match[1]:]) {
+			numbers = append(numbers, match)
+		}
+	}
+	if len(numbers) == 0 {
+		return matches[0]
+	}
+	return numbers[0]
+}
+
+// rank orders v's prerelease before its release.
+func (v Version) rank() (int, int) {
+	if v.Prerelease == "" {
+		return releaseRank, 0
+	}
+
+	tag := strings.TrimRight(v.Prerelease, "0123456789")
+	number, _ := strconv.Atoi(v.Prerelease[len(tag):])
+	return prereleaseRanks[tag], number
+}
+
+// SortKey returns a string which sorts like the version, so that versions can
+// be sorted and compared in range queries as strings.
+func (v Version) SortKey() string {
+	rank, number := v.rank()
+	return fmt.Sprintf("%08d.%08d.%08d.%d%04d", v.Major, v.Minor, v.Patch, rank, number)
+}
+
+// Compare returns -1, 0, or 1 if a is before, the same as, or after b.
+func Compare(a, b Version) int {
+	return strings.Compare(a.SortKey(), b.SortKey())
+}
+
+func (v Version) String() string {
+	if v.Release != "" {
+		return v.Release
+	}
+
+	parts := []string{strconv.Itoa(v.Major), strconv.Itoa(v.Minor), strconv.Itoa(v.Patch)}
+	result := strings.Join(parts[:v.Components], ".")
+	if v.Prerelease != "" {
+		result += "-" + v.Prerelease
+	}
+	return result
+}
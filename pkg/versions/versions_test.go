@@ -0,0 +1,97 @@
+package versions
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		s    string
+		want Version
+	}{
+		{s: "2", want: Version{Components: 1, Major: 2}},
+		{s: "v2.3.1", want: Version{Components: 3, Major: 2, Minor: 3, Patch: 1}},
+		{s: "version 9.4 (SAS Institute)", want: Version{Components: 2, Major: 9, Minor: 4}},
+		{s: "ver. 1.52", want: Version{Components: 2, Major: 1, Minor: 52}},
+		{s: "R2019b", want: Version{Components: 2, Major: 2019, Minor: 2, Release: "R2019b"}},
+		{s: "MATLAB r2014A", want: Version{Components: 2, Major: 2014, Minor: 1, Release: "R2014a"}},
+		{s: "1.2b3", want: Version{Components: 2, Major: 1, Minor: 2, Prerelease: "beta3"}},
+		{s: "3.0-rc1", want: Version{Components: 2, Major: 3, Prerelease: "rc1"}},
+		{s: "0.9 alpha", want: Version{Components: 2, Minor: 9, Prerelease: "alpha"}},
+		{s: "1.2.3.4", want: Version{Components: 3, Major: 1, Minor: 2, Patch: 3}},
+		// A number after "version" is preferred to an earlier number.
+		{s: "Windows 10 version 2.3", want: Version{Components: 2, Major: 2, Minor: 3}},
+		// Architectures are never the version.
+		{s: "SPSS 64-bit 22", want: Version{Components: 1, Major: 22}},
+		{s: "64 bit 22.0", want: Version{Components: 2, Major: 22}},
+		{s: "64-bit", want: Version{Components: 1, Major: 64}},
+		// "v" inside a word is not a version marker.
+		{s: "rev 3 of server 4.1", want: Version{Components: 1, Major: 3}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.s, func(t *testing.T) {
+			got, ok := Parse(tc.s)
+			if !ok {
+				t.Fatal("got no version")
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParse_None(t *testing.T) {
+	for _, s := range []string{"", "latest", "beta", "123456789"} {
+		t.Run(s, func(t *testing.T) {
+			if got, ok := Parse(s); ok {
+				t.Errorf("got version %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// In ascending order.
+	sorted := []string{
+		"1.2-dev",
+		"1.2a1",
+		"1.2b3",
+		"1.2rc1",
+		"1.2",
+		"1.2.1",
+		"1.10",
+		"R2019a",
+		"R2019b",
+	}
+
+	for i := range len(sorted) - 1 {
+		a, _ := Parse(sorted[i])
+		b, _ := Parse(sorted[i+1])
+		if got := Compare(a, b); got != -1 {
+			t.Errorf("got Compare(%q, %q) %d, want -1", sorted[i], sorted[i+1], got)
+		}
+	}
+
+	a, _ := Parse("v1.2")
+	b, _ := Parse("1.2.0")
+	if got := Compare(a, b); got != 0 {
+		t.Errorf("got Compare(%q, %q) %d, want 0", "v1.2", "1.2.0", got)
+	}
+}
+
+func TestVersion_String(t *testing.T) {
+	for s, want := range map[string]string{
+		"v2.3.1":  "2.3.1",
+		"1.2b3":   "1.2-beta3",
+		"R2019b":  "R2019b",
+		"ver 9.4": "9.4",
+	} {
+		v, _ := Parse(s)
+		if got := v.String(); got != want {
+			t.Errorf("got %q for %q, want %q", got, s, want)
+		}
+	}
+}